@@ -0,0 +1,171 @@
+package apperror
+
+import (
+	"fmt"
+	"net/http"
+
+	"go-crud-employee/models"
+)
+
+// Code is a stable, machine-readable error identifier. Clients may switch on
+// it; the human-readable message may change.
+type Code string
+
+const (
+	// Request errors
+	CodeInvalidRequest   Code = "INVALID_REQUEST"
+	CodeValidationFailed Code = "VALIDATION_FAILED"
+	CodeInvalidID        Code = "INVALID_ID"
+	CodeNoFieldsToUpdate Code = "NO_FIELDS_TO_UPDATE"
+	CodeInvalidHireDate  Code = "INVALID_HIRE_DATE"
+	CodeInvalidPassword  Code = "INVALID_PASSWORD"
+	CodeInvalidCallback  Code = "INVALID_CALLBACK"
+	CodeRateLimited      Code = "RATE_LIMITED"
+	CodeBodyTooLarge     Code = "BODY_TOO_LARGE"
+	CodeUnsupportedMedia Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeBatchRejected    Code = "BATCH_REJECTED"
+	CodeNotApplied       Code = "NOT_APPLIED"
+
+	CodeInvalidIdempotencyKey    Code = "INVALID_IDEMPOTENCY_KEY"
+	CodeIdempotencyKeyReused     Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyKeyInProgress Code = "IDEMPOTENCY_KEY_IN_PROGRESS"
+
+	CodeInvalidWebhookURL Code = "INVALID_WEBHOOK_URL"
+
+	// Authentication and authorization errors
+	CodeUnauthenticated       Code = "UNAUTHENTICATED"
+	CodeInvalidToken          Code = "INVALID_TOKEN"
+	CodeInvalidCredentials    Code = "INVALID_CREDENTIALS"
+	CodeAuthenticationFailed  Code = "AUTHENTICATION_FAILED"
+	CodeAccountDisabled       Code = "ACCOUNT_DISABLED"
+	CodeAccountNotLinked      Code = "ACCOUNT_NOT_LINKED"
+	CodeAccountConflict       Code = "ACCOUNT_CONFLICT"
+	CodePasswordResetRequired Code = "PASSWORD_RESET_REQUIRED"
+	CodeForbidden             Code = "FORBIDDEN"
+	CodeSelfModification      Code = "SELF_MODIFICATION"
+
+	// Resource errors
+	CodeEmployeeNotFound  Code = "EMPLOYEE_NOT_FOUND"
+	CodeUserNotFound      Code = "USER_NOT_FOUND"
+	CodeSessionNotFound   Code = "SESSION_NOT_FOUND"
+	CodeWebhookNotFound   Code = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound  Code = "DELIVERY_NOT_FOUND"
+	CodeDeliveryNotDead   Code = "DELIVERY_NOT_DEAD"
+	CodeDuplicateNIP      Code = "DUPLICATE_NIP"
+	CodeDuplicateEmail    Code = "DUPLICATE_EMAIL"
+	CodeDuplicateUsername Code = "DUPLICATE_USERNAME"
+	CodeDuplicateValue    Code = "DUPLICATE_VALUE"
+
+	// Server errors
+	CodeClientClosedRequest Code = "CLIENT_CLOSED_REQUEST"
+	CodeTimeout             Code = "TIMEOUT"
+	CodeInternal            Code = "INTERNAL_ERROR"
+)
+
+// StatusClientClosedRequest is the non-standard status recorded when the
+// client disconnects before the response is ready
+const StatusClientClosedRequest = 499
+
+// Error is an error that knows how it is presented to API clients. Cause is
+// logged but never sent to the client.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	Detail  string
+	Fields  []models.FieldError
+	Cause   error
+}
+
+// New creates an application error
+func New(status int, code Code, message, detail string) *Error {
+	return &Error{Status: status, Code: code, Message: message, Detail: detail}
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Cause)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is matches errors by code, so errors.Is(err, ErrUserNotFound) holds for
+// copies made with WithDetail or WithCause
+func (e *Error) Is(target error) bool {
+	other, ok := target.(*Error)
+	return ok && other.Code == e.Code
+}
+
+// WithDetail returns a copy with a different detail message
+func (e *Error) WithDetail(detail string) *Error {
+	copied := *e
+	copied.Detail = detail
+	return &copied
+}
+
+// WithCause returns a copy carrying the underlying error for the logs
+func (e *Error) WithCause(cause error) *Error {
+	copied := *e
+	copied.Cause = cause
+	return &copied
+}
+
+// Internal wraps an unexpected error. The message describes the failed
+// operation; the cause stays in the logs.
+func Internal(message string, cause error) *Error {
+	return &Error{
+		Status:  http.StatusInternalServerError,
+		Code:    CodeInternal,
+		Message: message,
+		Detail:  "An unexpected error occurred",
+		Cause:   cause,
+	}
+}
+
+// Errors shared by several handlers
+var (
+	ErrUnauthenticated       = New(http.StatusUnauthorized, CodeUnauthenticated, "Unauthorized", "User not authenticated")
+	ErrInvalidToken          = New(http.StatusUnauthorized, CodeInvalidToken, "Invalid token", "Token is invalid or expired")
+	ErrInvalidCredentials    = New(http.StatusUnauthorized, CodeInvalidCredentials, "Authentication failed", "Invalid username or password")
+	ErrAccountDisabled       = New(http.StatusForbidden, CodeAccountDisabled, "Authentication failed", "This account has been disabled")
+	ErrAccountConflict       = New(http.StatusConflict, CodeAccountConflict, "Authentication failed", "The username or email of this directory account belongs to another local account")
+	ErrPasswordResetRequired = New(http.StatusForbidden, CodePasswordResetRequired, "Password reset required", "Change your password via /api/v1/auth/change-password")
+	ErrForbidden             = New(http.StatusForbidden, CodeForbidden, "Forbidden", "You do not have permission to access this resource")
+	ErrWeakPassword          = New(http.StatusBadRequest, CodeInvalidPassword, "Invalid password", "Password must be at least 6 characters long")
+	ErrNoFieldsToUpdate      = New(http.StatusBadRequest, CodeNoFieldsToUpdate, "No fields to update", "At least one field must be provided for update")
+
+	ErrInvalidEmployeeID = New(http.StatusBadRequest, CodeInvalidID, "Invalid employee ID", "Employee ID must be a number")
+	ErrInvalidUserID     = New(http.StatusBadRequest, CodeInvalidID, "Invalid user ID", "User ID must be a number")
+	ErrEmployeeNotFound  = New(http.StatusNotFound, CodeEmployeeNotFound, "Employee not found", "Employee with the specified ID does not exist")
+	ErrUserNotFound      = New(http.StatusNotFound, CodeUserNotFound, "User not found", "User with the specified ID does not exist")
+	ErrSessionNotFound   = New(http.StatusNotFound, CodeSessionNotFound, "Session not found", "Session with the specified ID does not exist")
+	ErrInvalidWebhookID  = New(http.StatusBadRequest, CodeInvalidID, "Invalid webhook ID", "Webhook ID must be a number")
+	ErrInvalidDeliveryID = New(http.StatusBadRequest, CodeInvalidID, "Invalid delivery ID", "Delivery ID must be a number")
+	ErrWebhookNotFound   = New(http.StatusNotFound, CodeWebhookNotFound, "Webhook not found", "Webhook with the specified ID does not exist")
+	ErrDeliveryNotFound  = New(http.StatusNotFound, CodeDeliveryNotFound, "Delivery not found", "Delivery with the specified ID does not exist for this webhook")
+	ErrDeliveryNotDead   = New(http.StatusConflict, CodeDeliveryNotDead, "Delivery not dead", "Only dead deliveries can be retried")
+	ErrInvalidWebhookURL = New(http.StatusBadRequest, CodeInvalidWebhookURL, "Invalid webhook URL", "Webhook URL must use http or https")
+	ErrInvalidEventID    = New(http.StatusBadRequest, CodeInvalidID, "Invalid event ID", "Last-Event-ID must be the ID of an event from this stream")
+
+	ErrDuplicateNIP      = New(http.StatusConflict, CodeDuplicateNIP, "NIP already exists", "Employee with this NIP already exists")
+	ErrDuplicateEmail    = New(http.StatusConflict, CodeDuplicateEmail, "Email already exists", "Please use a different email address")
+	ErrDuplicateUsername = New(http.StatusConflict, CodeDuplicateUsername, "Username already exists", "Please choose a different username")
+	ErrDuplicateValue    = New(http.StatusConflict, CodeDuplicateValue, "Duplicate value", "A record with the same unique value already exists")
+
+	ErrBodyTooLarge     = New(http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "Request body too large", "The request body exceeds the size limit of this endpoint")
+	ErrUnsupportedMedia = New(http.StatusUnsupportedMediaType, CodeUnsupportedMedia, "Unsupported media type", "The Content-Type of the request body is not accepted by this endpoint")
+	ErrBatchRejected    = New(http.StatusUnprocessableEntity, CodeBatchRejected, "Batch rejected", "At least one operation failed, so none were applied")
+	ErrNotApplied       = New(http.StatusFailedDependency, CodeNotApplied, "Not applied", "Another operation in the atomic batch failed")
+	ErrRateLimited      = New(http.StatusTooManyRequests, CodeRateLimited, "Too many requests", "Rate limit exceeded, retry after the time given in the Retry-After header")
+
+	ErrInvalidIdempotencyKey    = New(http.StatusBadRequest, CodeInvalidIdempotencyKey, "Invalid idempotency key", "Idempotency-Key must be 1 to 255 printable ASCII characters")
+	ErrIdempotencyKeyReused     = New(http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "Idempotency key reused", "This Idempotency-Key was already used for a different request")
+	ErrIdempotencyKeyInProgress = New(http.StatusConflict, CodeIdempotencyKeyInProgress, "Request in progress", "A request with this Idempotency-Key is still being processed; retry later")
+
+	ErrClientClosedRequest = New(StatusClientClosedRequest, CodeClientClosedRequest, "Request canceled", "The client closed the request")
+	ErrTimeout             = New(http.StatusGatewayTimeout, CodeTimeout, "Request timed out", "The database did not respond in time")
+)
@@ -0,0 +1,35 @@
+package apperror
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the SQLSTATE of a unique constraint violation
+const uniqueViolation = "23505"
+
+// uniqueConstraints maps unique constraints, by the names Postgres gives the
+// UNIQUE columns in the initial migration, to the error clients receive.
+// Relying on the constraint instead of checking first keeps concurrent
+// creates from both passing the check.
+var uniqueConstraints = map[string]*Error{
+	"employees_nip_key":   ErrDuplicateNIP,
+	"employees_email_key": ErrDuplicateEmail.WithDetail("Employee with this email already exists"),
+	"users_username_key":  ErrDuplicateUsername,
+	"users_email_key":     ErrDuplicateEmail,
+}
+
+// FromPostgres translates constraint violations into client errors. It
+// returns nil for errors that are not the client's fault.
+func FromPostgres(err error) *Error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != uniqueViolation {
+		return nil
+	}
+
+	if appErr, ok := uniqueConstraints[pqErr.Constraint]; ok {
+		return appErr.WithCause(err)
+	}
+	return ErrDuplicateValue.WithCause(err)
+}
@@ -0,0 +1,50 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestFromPostgres(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"nip", &pq.Error{Code: uniqueViolation, Constraint: "employees_nip_key"}, CodeDuplicateNIP},
+		{"employee email", &pq.Error{Code: uniqueViolation, Constraint: "employees_email_key"}, CodeDuplicateEmail},
+		{"username", &pq.Error{Code: uniqueViolation, Constraint: "users_username_key"}, CodeDuplicateUsername},
+		{"user email", &pq.Error{Code: uniqueViolation, Constraint: "users_email_key"}, CodeDuplicateEmail},
+		{"other constraint", &pq.Error{Code: uniqueViolation, Constraint: "webhook_subscriptions_url_key"}, CodeDuplicateValue},
+		{"wrapped", fmt.Errorf("failed to insert: %w", &pq.Error{Code: uniqueViolation, Constraint: "employees_nip_key"}), CodeDuplicateNIP},
+		{"not null violation", &pq.Error{Code: "23502", Constraint: "employees_nip_key"}, ""},
+		{"not postgres", errors.New("connection refused"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromPostgres(tt.err)
+			if tt.want == "" {
+				if got != nil {
+					t.Fatalf("FromPostgres = %v, want nil", got.Code)
+				}
+				return
+			}
+			if got == nil || got.Code != tt.want {
+				t.Fatalf("FromPostgres = %v, want %s", got, tt.want)
+			}
+			if got.Status != http.StatusConflict || !errors.Is(got.Cause, tt.err) {
+				t.Errorf("FromPostgres = status %d, cause %v; want 409 caused by the driver error", got.Status, got.Cause)
+			}
+		})
+	}
+
+	// The shared errors are not modified
+	FromPostgres(&pq.Error{Code: uniqueViolation, Constraint: "employees_nip_key"})
+	if ErrDuplicateNIP.Cause != nil {
+		t.Error("FromPostgres set the cause of ErrDuplicateNIP")
+	}
+}
@@ -0,0 +1,132 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"go-crud-employee/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// UseJSONFieldNames makes validation errors name fields as clients send them
+// (json or form tag) instead of by Go struct field
+func UseJSONFieldNames() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// InvalidRequest converts an error from ShouldBindJSON / ShouldBindQuery
+// into a 400, with one entry per rejected field where possible
+func InvalidRequest(err error) *Error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return ErrBodyTooLarge.WithCause(err)
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]models.FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, models.FieldError{
+				Field:   fieldPath(fieldErr),
+				Rule:    fieldErr.Tag(),
+				Message: ruleMessage(fieldErr),
+			})
+		}
+		return &Error{
+			Status:  http.StatusBadRequest,
+			Code:    CodeValidationFailed,
+			Message: "Invalid request data",
+			Detail:  "One or more fields are invalid",
+			Fields:  fields,
+			Cause:   err,
+		}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		appErr := New(http.StatusBadRequest, CodeValidationFailed, "Invalid request data", "One or more fields are invalid")
+		appErr.Fields = []models.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be of type %s", typeErr.Type.String()),
+		}}
+		return appErr
+	}
+
+	// Reported by decoders with DisallowUnknownFields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		appErr := New(http.StatusBadRequest, CodeValidationFailed, "Invalid request data", "One or more fields are invalid")
+		appErr.Fields = []models.FieldError{{
+			Field:   strings.Trim(field, `"`),
+			Rule:    "unknown",
+			Message: "is not a recognized field",
+		}}
+		return appErr
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return New(http.StatusBadRequest, CodeInvalidRequest, "Invalid request data",
+			fmt.Sprintf("Malformed JSON at offset %d", syntaxErr.Offset))
+	}
+
+	return New(http.StatusBadRequest, CodeInvalidRequest, "Invalid request data", "The request body or query could not be parsed").WithCause(err)
+}
+
+// fieldPath drops the top-level struct name from the namespace, leaving
+// e.g. "email" or "items[0].nip"
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fieldErr.Field()
+}
+
+// ruleMessage explains a failed validation rule in plain words
+func ruleMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fieldErr.Param())
+	case "lte":
+		return fmt.Sprintf("must be less than or equal to %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	}
+	return fmt.Sprintf("failed the %q rule", fieldErr.Tag())
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-crud-employee/config"
+	"go-crud-employee/database"
+	"go-crud-employee/models"
+	"go-crud-employee/utils"
+)
+
+// ErrInvalidCredentials is returned when the username or password is wrong
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrAccountConflict is returned when an external identity cannot be cached
+// because its username or email belongs to another local account
+var ErrAccountConflict = errors.New("account conflicts with an existing user")
+
+// Authenticator verifies a username and password and returns the local user.
+// Account status (disabled, reset required) is left to the caller.
+type Authenticator interface {
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+}
+
+// NewAuthenticator builds the authenticator chain configured in AUTH_BACKENDS
+func NewAuthenticator(cfg *config.Config, db *database.DB) (Authenticator, error) {
+	var backends []Authenticator
+	for _, name := range cfg.Auth.Backends {
+		switch name {
+		case "local":
+			backends = append(backends, NewLocalAuthenticator(db))
+		case "ldap":
+			ldapAuth, err := NewLDAPAuthenticator(cfg.LDAP, db)
+			if err != nil {
+				return nil, err
+			}
+			backends = append(backends, ldapAuth)
+		default:
+			return nil, fmt.Errorf("unknown authentication backend %q", name)
+		}
+	}
+
+	switch len(backends) {
+	case 0:
+		return nil, fmt.Errorf("no authentication backend configured")
+	case 1:
+		return backends[0], nil
+	default:
+		return NewChainAuthenticator(backends...), nil
+	}
+}
+
+// LocalAuthenticator checks bcrypt password hashes stored in the users table
+type LocalAuthenticator struct {
+	db *database.DB
+}
+
+func NewLocalAuthenticator(db *database.DB) *LocalAuthenticator {
+	return &LocalAuthenticator{
+		db: db,
+	}
+}
+
+// Authenticate implements Authenticator
+func (a *LocalAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	var user models.User
+	query := `SELECT id, username, email, password_hash, role, is_active, password_reset_required, created_at, updated_at
+			  FROM users WHERE username = $1`
+
+	err := a.db.QueryRowContext(ctx, query, username).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&user.Role,
+		&user.IsActive,
+		&user.PasswordResetRequired,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to look up user: %v", err)
+	}
+
+	if err := utils.CheckPassword(password, user.PasswordHash); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &user, nil
+}
+
+// ChainAuthenticator tries each backend in order and returns the first success
+type ChainAuthenticator struct {
+	backends []Authenticator
+}
+
+func NewChainAuthenticator(backends ...Authenticator) *ChainAuthenticator {
+	return &ChainAuthenticator{
+		backends: backends,
+	}
+}
+
+// Authenticate implements Authenticator. Backend failures other than bad
+// credentials are reported only if no later backend accepts the user.
+func (a *ChainAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	var lastErr error
+	for _, backend := range a.backends {
+		user, err := backend.Authenticate(ctx, username, password)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			lastErr = err
+		}
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrInvalidCredentials
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/config"
+	"go-crud-employee/database"
+	"go-crud-employee/models"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPAuthenticator verifies passwords with an LDAP bind and caches the
+// directory user in the local users table on each successful login
+type LDAPAuthenticator struct {
+	cfg config.LDAPConfig
+	db  *database.DB
+}
+
+func NewLDAPAuthenticator(cfg config.LDAPConfig, db *database.DB) (*LDAPAuthenticator, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, fmt.Errorf("LDAP_URL and LDAP_BASE_DN are required for the ldap backend")
+	}
+	if strings.Count(cfg.UserFilter, "%s") != 1 {
+		return nil, fmt.Errorf("LDAP_USER_FILTER must contain exactly one %%s")
+	}
+
+	return &LDAPAuthenticator{
+		cfg: cfg,
+		db:  db,
+	}, nil
+}
+
+// Authenticate implements Authenticator
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	// An empty password would be an unauthenticated bind, which most servers accept
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Look the user up with the service account (or anonymously)
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP service bind failed: %v", err)
+		}
+	}
+
+	search := ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, // Only need to know whether the match is unique
+		int(a.cfg.Timeout.Seconds()),
+		false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"dn", a.cfg.EmailAttribute, a.cfg.GroupAttribute},
+		nil,
+	)
+
+	result, err := conn.Search(search)
+	if err != nil {
+		// More matches than the size limit means the username is ambiguous
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP search failed: %v", err)
+	}
+
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	// Verify the password by binding as the user
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP user bind failed: %v", err)
+	}
+
+	email := entry.GetAttributeValue(a.cfg.EmailAttribute)
+	if email == "" {
+		return nil, fmt.Errorf("LDAP entry %s has no %s attribute", entry.DN, a.cfg.EmailAttribute)
+	}
+
+	role := a.mapRole(entry.GetAttributeValues(a.cfg.GroupAttribute))
+
+	return a.syncUser(ctx, username, email, role)
+}
+
+func (a *LDAPAuthenticator) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: a.cfg.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(a.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: a.cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %v", err)
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+
+	if a.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS failed: %v", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// mapRole picks the most privileged role granted by the user's groups.
+// Groups may be listed as full DNs; only the CN is matched.
+func (a *LDAPAuthenticator) mapRole(groups []string) string {
+	for _, group := range groups {
+		name := group
+		if dn, err := ldap.ParseDN(group); err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+			name = dn.RDNs[0].Attributes[0].Value
+		}
+
+		for configured, mapped := range a.cfg.GroupRoles {
+			if strings.EqualFold(configured, name) && mapped == models.RoleAdmin {
+				return models.RoleAdmin
+			}
+		}
+	}
+	return models.RoleUser
+}
+
+// syncUser creates or refreshes the local copy of a directory user. Local
+// accounts with the same username or email are never taken over.
+func (a *LDAPAuthenticator) syncUser(ctx context.Context, username, email, role string) (*models.User, error) {
+	var user models.User
+	query := `INSERT INTO users (username, email, password_hash, role, auth_source, created_at, updated_at)
+			  VALUES ($1, $2, '', $3, 'ldap', $4, $4)
+			  ON CONFLICT (username) DO UPDATE
+			  SET email = EXCLUDED.email, role = EXCLUDED.role, password_reset_required = false,
+			      updated_at = EXCLUDED.updated_at
+			  WHERE users.auth_source = 'ldap'
+			  RETURNING id, username, email, role, is_active, password_reset_required, created_at, updated_at`
+
+	err := a.db.QueryRowContext(ctx, query, username, email, role, time.Now()).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Role,
+		&user.IsActive,
+		&user.PasswordResetRequired,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: username %q belongs to a local account", ErrAccountConflict, username)
+		}
+		if apperror.FromPostgres(err) != nil {
+			return nil, fmt.Errorf("%w: email %q belongs to another account", ErrAccountConflict, email)
+		}
+		return nil, fmt.Errorf("failed to cache LDAP user: %v", err)
+	}
+
+	return &user, nil
+}
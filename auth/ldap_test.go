@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-crud-employee/config"
+	"go-crud-employee/database/dbtest"
+	"go-crud-employee/models"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAP protocol operations and result codes used by the test server
+const (
+	opBindRequest      = 0
+	opBindResponse     = 1
+	opUnbindRequest    = 2
+	opSearchRequest    = 3
+	opSearchEntry      = 4
+	opSearchResultDone = 5
+)
+
+type ldapEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// ldapServer is a minimal in-process directory. It understands simple
+// binds and searches with an equality filter, and returns at most sizeLimit
+// entries like a server with an administrative size limit.
+type ldapServer struct {
+	listener  net.Listener
+	entries   []ldapEntry
+	sizeLimit atomic.Int32
+}
+
+func newLDAPServer(t *testing.T, entries ...ldapEntry) *ldapServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &ldapServer{listener: listener, entries: entries}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *ldapServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *ldapServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *ldapServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case opBindRequest:
+			dn := op.Children[1].Data.String()
+			password := op.Children[2].Data.String()
+			code := uint16(ldap.LDAPResultInvalidCredentials)
+			if dn == "" && password == "" {
+				code = ldap.LDAPResultSuccess
+			}
+			for _, entry := range s.entries {
+				if entry.dn == dn && entry.password == password {
+					code = ldap.LDAPResultSuccess
+				}
+			}
+			s.write(conn, id, result(opBindResponse, code))
+		case opSearchRequest:
+			s.search(conn, id, op)
+		case opUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *ldapServer) search(conn net.Conn, id int64, op *ber.Packet) {
+	filter, err := ldap.DecompileFilter(op.Children[6])
+	if err != nil {
+		s.write(conn, id, result(opSearchResultDone, ldap.LDAPResultProtocolError))
+		return
+	}
+	attr, value, _ := strings.Cut(strings.Trim(filter, "()"), "=")
+
+	sent := 0
+	for _, entry := range s.entries {
+		if !contains(entry.attrs[attr], value) {
+			continue
+		}
+		if limit := int(s.sizeLimit.Load()); limit > 0 && sent == limit {
+			s.write(conn, id, result(opSearchResultDone, ldap.LDAPResultSizeLimitExceeded))
+			return
+		}
+		s.write(conn, id, searchEntry(entry))
+		sent++
+	}
+	s.write(conn, id, result(opSearchResultDone, ldap.LDAPResultSuccess))
+}
+
+func (s *ldapServer) write(conn net.Conn, id int64, op *ber.Packet) {
+	packet := ber.NewSequence("LDAP Message")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	packet.AppendChild(op)
+	conn.Write(packet.Bytes())
+}
+
+func result(tag ber.Tag, code uint16) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, uint64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return op
+}
+
+func searchEntry(entry ldapEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "Object Name"))
+	attrs := ber.NewSequence("Attributes")
+	for name, values := range entry.attrs {
+		attr := ber.NewSequence("Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+	op.AppendChild(attrs)
+	return op
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func person(uid, password, email string, groups ...string) ldapEntry {
+	return ldapEntry{
+		dn:       "uid=" + uid + ",ou=people,dc=example,dc=com",
+		password: password,
+		attrs:    map[string][]string{"uid": {uid}, "mail": {email}, "memberOf": groups},
+	}
+}
+
+func newTestLDAPAuthenticator(t *testing.T, server *ldapServer) *LDAPAuthenticator {
+	t.Helper()
+	a, err := NewLDAPAuthenticator(config.LDAPConfig{
+		URL:            server.URL(),
+		BaseDN:         "dc=example,dc=com",
+		UserFilter:     "(uid=%s)",
+		EmailAttribute: "mail",
+		GroupAttribute: "memberOf",
+		GroupRoles:     map[string]string{"hr-admins": models.RoleAdmin},
+		Timeout:        2 * time.Second,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestLDAPRejects(t *testing.T) {
+	server := newLDAPServer(t,
+		person("rina", "secret", "rina@example.com"),
+		person("dup", "secret", "dup1@example.com"),
+		person("dup", "secret", "dup2@example.com"),
+	)
+	a := newTestLDAPAuthenticator(t, server)
+
+	tests := []struct {
+		name      string
+		username  string
+		password  string
+		sizeLimit int
+	}{
+		{"wrong password", "rina", "wrong", 0},
+		{"empty password", "rina", "", 0},
+		{"unknown user", "nobody", "secret", 0},
+		{"ambiguous user", "dup", "secret", 0},
+		{"server size limit", "dup", "secret", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.sizeLimit.Store(int32(tt.sizeLimit))
+			_, err := a.Authenticate(context.Background(), tt.username, tt.password)
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("Authenticate = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestLDAPMapRole(t *testing.T) {
+	a := newTestLDAPAuthenticator(t, newLDAPServer(t))
+
+	tests := []struct {
+		groups []string
+		want   string
+	}{
+		{nil, models.RoleUser},
+		{[]string{"cn=staff,ou=groups,dc=example,dc=com"}, models.RoleUser},
+		{[]string{"cn=staff,ou=groups,dc=example,dc=com", "cn=HR-Admins,ou=groups,dc=example,dc=com"}, models.RoleAdmin},
+		{[]string{"hr-admins"}, models.RoleAdmin},
+	}
+	for _, tt := range tests {
+		if got := a.mapRole(tt.groups); got != tt.want {
+			t.Errorf("mapRole(%v) = %q, want %q", tt.groups, got, tt.want)
+		}
+	}
+}
+
+func TestLDAPSyncUser(t *testing.T) {
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "users")
+	server := newLDAPServer(t,
+		person("rina", "secret", "rina@example.com", "cn=hr-admins,ou=groups,dc=example,dc=com"),
+		person("budi", "secret", "budi@example.com"),
+		person("sari", "secret", "local@example.com"),
+	)
+	a := newTestLDAPAuthenticator(t, server)
+	a.db = db
+
+	ctx := context.Background()
+	_, err := db.ExecContext(ctx, `INSERT INTO users (username, email, password_hash, role)
+		VALUES ('budi', 'budi.local@example.com', 'x', 'user'), ('local', 'local@example.com', 'x', 'user')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := a.Authenticate(ctx, "rina", "secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if user.Email != "rina@example.com" || user.Role != models.RoleAdmin {
+		t.Errorf("user = %+v, want rina@example.com as admin", user)
+	}
+
+	// A directory user sharing a local account's username or email is a
+	// conflict, not a server error, even when the local backend runs first
+	chain := NewChainAuthenticator(NewLocalAuthenticator(db), a)
+	for _, username := range []string{"budi", "sari"} {
+		if _, err := chain.Authenticate(ctx, username, "secret"); !errors.Is(err, ErrAccountConflict) {
+			t.Errorf("Authenticate(%s) = %v, want ErrAccountConflict", username, err)
+		}
+	}
+}
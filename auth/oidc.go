@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"go-crud-employee/config"
+	"go-crud-employee/models"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCIdentity is the subset of ID token claims used to map a local user
+type OIDCIdentity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Roles             []string
+}
+
+// OIDCClient runs the authorization code flow with PKCE against an
+// OpenID Connect provider discovered from its issuer URL
+type OIDCClient struct {
+	cfg      config.OIDCConfig
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCClient fetches the provider's discovery document and prepares the
+// ID token verifier, which validates signatures against the provider's JWKS
+func NewOIDCClient(ctx context.Context, cfg config.OIDCConfig) (*OIDCClient, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" {
+		return nil, fmt.Errorf("OIDC_ISSUER_URL and OIDC_CLIENT_ID are required")
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %v", err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID}
+	}
+
+	return &OIDCClient{
+		cfg: cfg,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+// NewLoginState returns fresh random values for the state, nonce and PKCE
+// code verifier, and a binding kept in a cookie of the browser that starts
+// the login
+func NewLoginState() (state, nonce, codeVerifier, binding string, err error) {
+	if state, err = randomString(); err != nil {
+		return "", "", "", "", err
+	}
+	if nonce, err = randomString(); err != nil {
+		return "", "", "", "", err
+	}
+	if binding, err = randomString(); err != nil {
+		return "", "", "", "", err
+	}
+	return state, nonce, oauth2.GenerateVerifier(), binding, nil
+}
+
+// AuthCodeURL builds the provider URL the browser is redirected to
+func (o *OIDCClient) AuthCodeURL(state, nonce, codeVerifier string) string {
+	return o.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier))
+}
+
+// Exchange redeems an authorization code and returns the verified identity
+func (o *OIDCClient) Exchange(ctx context.Context, code, nonce, codeVerifier string) (*OIDCIdentity, error) {
+	token, err := o.oauth2.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %v", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("token response did not include an id_token")
+	}
+
+	idToken, err := o.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id_token: %v", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, fmt.Errorf("id_token nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse id_token claims: %v", err)
+	}
+
+	identity := &OIDCIdentity{
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+	}
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.PreferredUsername, _ = claims["preferred_username"].(string)
+
+	// The role claim may be a single string or a list of strings
+	switch roles := claims[o.cfg.RoleClaim].(type) {
+	case string:
+		identity.Roles = []string{roles}
+	case []interface{}:
+		for _, role := range roles {
+			if value, ok := role.(string); ok {
+				identity.Roles = append(identity.Roles, value)
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+// ManagesRoles reports whether local roles follow the provider's claims.
+// Without OIDC_ADMIN_VALUES the claims cannot grant admin, so roles are
+// left to local administration.
+func (o *OIDCClient) ManagesRoles() bool {
+	return len(o.cfg.AdminValues) > 0
+}
+
+// SecureCallback reports whether the callback is served over HTTPS
+func (o *OIDCClient) SecureCallback() bool {
+	return strings.HasPrefix(o.cfg.RedirectURL, "https://")
+}
+
+// MapRole translates the identity's role claim values into a local role
+func (o *OIDCClient) MapRole(identity *OIDCIdentity) string {
+	for _, role := range identity.Roles {
+		for _, adminValue := range o.cfg.AdminValues {
+			if role == adminValue {
+				return models.RoleAdmin
+			}
+		}
+	}
+	return models.RoleUser
+}
+
+// AutoProvision reports whether unknown identities get a local user created
+func (o *OIDCClient) AutoProvision() bool {
+	return o.cfg.AutoProvision
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
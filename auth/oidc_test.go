@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go-crud-employee/auth/oidctest"
+	"go-crud-employee/config"
+	"go-crud-employee/models"
+)
+
+func newTestOIDCClient(t *testing.T, adminValues ...string) (*OIDCClient, *oidctest.Provider) {
+	t.Helper()
+
+	provider := oidctest.NewProvider(t, "employee-api")
+	client, err := NewOIDCClient(context.Background(), config.OIDCConfig{
+		IssuerURL:   provider.URL,
+		ClientID:    provider.ClientID,
+		RedirectURL: "https://api.example.com/api/v1/auth/oidc/callback",
+		RoleClaim:   "groups",
+		AdminValues: adminValues,
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCClient: %v", err)
+	}
+	return client, provider
+}
+
+func TestOIDCExchange(t *testing.T) {
+	client, provider := newTestOIDCClient(t, "hr-admins")
+
+	state, nonce, verifier, _, err := NewLoginState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL := client.AuthCodeURL(state, nonce, verifier)
+	if got := mustQuery(t, authURL).Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+
+	code := provider.Authorize(t, authURL, map[string]any{
+		"sub":                "user-1",
+		"email":              "rina@example.com",
+		"email_verified":     true,
+		"preferred_username": "rina",
+		"groups":             []string{"staff", "hr-admins"},
+	})
+
+	identity, err := client.Exchange(context.Background(), code, nonce, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Issuer != provider.URL || identity.Subject != "user-1" {
+		t.Errorf("identity = %s/%s, want %s/user-1", identity.Issuer, identity.Subject, provider.URL)
+	}
+	if identity.Email != "rina@example.com" || !identity.EmailVerified || identity.PreferredUsername != "rina" {
+		t.Errorf("unexpected identity claims: %+v", identity)
+	}
+	if role := client.MapRole(identity); role != models.RoleAdmin {
+		t.Errorf("MapRole = %q, want %q", role, models.RoleAdmin)
+	}
+}
+
+func TestOIDCExchangeRejects(t *testing.T) {
+	client, provider := newTestOIDCClient(t)
+
+	tests := []struct {
+		name     string
+		exchange func(code, nonce, verifier string) error
+	}{
+		{"wrong code verifier", func(code, nonce, verifier string) error {
+			_, err := client.Exchange(context.Background(), code, nonce, verifier+"x")
+			return err
+		}},
+		{"nonce mismatch", func(code, nonce, verifier string) error {
+			_, err := client.Exchange(context.Background(), code, "other-nonce", verifier)
+			return err
+		}},
+		{"code redeemed twice", func(code, nonce, verifier string) error {
+			if _, err := client.Exchange(context.Background(), code, nonce, verifier); err != nil {
+				t.Fatalf("first Exchange: %v", err)
+			}
+			_, err := client.Exchange(context.Background(), code, nonce, verifier)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, nonce, verifier, _, err := NewLoginState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			code := provider.Authorize(t, client.AuthCodeURL(state, nonce, verifier), nil)
+			if err := tt.exchange(code, nonce, verifier); err == nil {
+				t.Fatal("Exchange succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestOIDCRoleMapping(t *testing.T) {
+	unmanaged, _ := newTestOIDCClient(t)
+	if unmanaged.ManagesRoles() {
+		t.Error("ManagesRoles = true without OIDC_ADMIN_VALUES")
+	}
+
+	managed, _ := newTestOIDCClient(t, "hr-admins")
+	if !managed.ManagesRoles() {
+		t.Error("ManagesRoles = false with OIDC_ADMIN_VALUES")
+	}
+	if role := managed.MapRole(&OIDCIdentity{Roles: []string{"staff"}}); role != models.RoleUser {
+		t.Errorf("MapRole = %q, want %q", role, models.RoleUser)
+	}
+	if !managed.SecureCallback() {
+		t.Error("SecureCallback = false for an https redirect URL")
+	}
+}
+
+func mustQuery(t *testing.T, rawURL string) url.Values {
+	t.Helper()
+	parsed, err := url.Parse(rawURL)
+	if err != nil || !strings.HasPrefix(rawURL, "http") {
+		t.Fatalf("invalid URL %q: %v", rawURL, err)
+	}
+	return parsed.Query()
+}
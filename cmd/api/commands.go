@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"go-crud-employee/config"
+	"go-crud-employee/database"
+	"go-crud-employee/logging"
+	"go-crud-employee/tlsutil"
+)
+
+const usage = `usage: api <command> [arguments]
+
+Commands:
+  serve                                       start the API server (default)
+  migrate up | down [steps] | status          manage the database schema
+  user create [--role r] [--password-stdin] <username> <email>
+  user set-password [--password-stdin] [--require-reset] <username>
+  user disable <username>
+  employees import [--dry-run] <file.csv|file.json>
+  seed [--employees n] [--users n] [--seed s]  add generated test data
+  config check | print [--redacted]           validate or show the configuration
+  tls dev-certs <dir> [--client-cn name]      generate certificates for local TLS
+  openapi [--check]                           print the OpenAPI document or check it covers every route`
+
+// runCommand runs an administrative subcommand and returns the exit code
+func runCommand(args []string) int {
+	commands := map[string]func([]string) int{
+		"migrate":   migrateCommand,
+		"user":      userCommand,
+		"employees": employeesCommand,
+		"seed":      seedCommand,
+		"config":    configCommand,
+		"tls":       tlsCommand,
+		"openapi":   openapiCommand,
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return command(args[1:])
+}
+
+// openDB loads the configuration and connects to the database for commands
+// that need it. Logs go to stderr so stdout stays parseable.
+func openDB() (*config.Config, *database.DB, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%v", err)
+	}
+	if err := logging.Setup(os.Stderr, cfg.Log.Level); err != nil {
+		return nil, nil, err
+	}
+
+	db, err := database.NewConnection(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, db, nil
+}
+
+// failed reports a command error and returns the exit code for it
+func failed(err error) int {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	return 1
+}
+
+// migrateCommand applies, reverts or lists schema migrations
+func migrateCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: api migrate up | down [steps] | status")
+		return 2
+	}
+
+	steps := 1
+	if args[0] == "down" && len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			fmt.Fprintln(os.Stderr, "steps must be a positive number")
+			return 2
+		}
+		steps = n
+	}
+
+	_, db, err := openDB()
+	if err != nil {
+		return failed(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(ctx)
+		if err != nil {
+			return failed(err)
+		}
+		fmt.Printf("Applied %d migration(s)\n", len(applied))
+	case "down":
+		reverted, err := db.MigrateDown(ctx, steps)
+		if err != nil {
+			return failed(err)
+		}
+		fmt.Printf("Reverted %d migration(s)\n", len(reverted))
+	case "status":
+		states, err := db.MigrationStatus(ctx)
+		if err != nil {
+			return failed(err)
+		}
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt != nil {
+				applied = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s  %s\n", state.Version, state.Name, applied)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n", args[0])
+		return 2
+	}
+	return 0
+}
+
+// configCommand validates or prints the effective configuration
+func configCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: api config print [--redacted] | api config check")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	redacted := flags.Bool("redacted", false, "mask passwords, secrets and tokens")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "check":
+		fmt.Println("Configuration is valid")
+	case "print":
+		// Printed in .env syntax so the output can be reused as a file
+		for _, setting := range cfg.Settings(*redacted) {
+			fmt.Printf("%s=%s # %s\n", setting.Key, setting.Value, setting.Source)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config command %q\n", args[0])
+		return 2
+	}
+	return 0
+}
+
+// tlsCommand generates certificates for trying TLS and mTLS locally
+func tlsCommand(args []string) int {
+	if len(args) < 2 || args[0] != "dev-certs" {
+		fmt.Fprintln(os.Stderr, "usage: api tls dev-certs <dir> [--client-cn name]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("tls dev-certs", flag.ContinueOnError)
+	clientCN := flags.String("client-cn", "service-client", "common name of the client certificate")
+	if err := flags.Parse(args[2:]); err != nil {
+		return 2
+	}
+
+	if err := tlsutil.WriteDevCerts(args[1], *clientCN); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write certificates: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote ca.pem, server.pem, server-key.pem, client.pem and client-key.pem to %s\n", args[1])
+	return 0
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/models"
+	"go-crud-employee/webhook"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// employeeColumns are the CSV header names, matching the JSON field names
+var employeeColumns = []string{"nip", "name", "email", "phone", "position", "department", "salary", "hire_date"}
+
+// employeesCommand bulk-loads employees from a file
+func employeesCommand(args []string) int {
+	if len(args) == 0 || args[0] != "import" {
+		fmt.Fprintln(os.Stderr, "usage: api employees import [--dry-run] <file.csv|file.json>")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("employees import", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "validate the file without importing it")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: api employees import [--dry-run] <file.csv|file.json>")
+		return 2
+	}
+
+	employees, err := readEmployees(flags.Arg(0))
+	if err != nil {
+		return failed(err)
+	}
+
+	// Report every invalid row before touching the database
+	apperror.UseJSONFieldNames()
+	var invalid []error
+	for i, employee := range employees {
+		if err := validateEmployee(employee); err != nil {
+			invalid = append(invalid, fmt.Errorf("row %d: %v", i+1, err))
+		}
+	}
+	if len(invalid) > 0 {
+		return failed(fmt.Errorf("%d invalid row(s), nothing imported:\n%v", len(invalid), errors.Join(invalid...)))
+	}
+	if *dryRun {
+		fmt.Printf("%d employee(s) are valid\n", len(employees))
+		return 0
+	}
+
+	_, db, err := openDB()
+	if err != nil {
+		return failed(err)
+	}
+	defer db.Close()
+
+	// All rows go in one transaction, so a failing row imports nothing
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return failed(fmt.Errorf("failed to start transaction: %v", err))
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO employees (nip, name, email, phone, position, department, salary, hire_date, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, true, $9, $9)
+		RETURNING id, nip, name, email, phone, position, department, salary, hire_date, is_active, created_at, updated_at`)
+	if err != nil {
+		return failed(fmt.Errorf("failed to prepare insert: %v", err))
+	}
+	defer stmt.Close()
+
+	// Imported employees are announced to webhook subscribers like any other
+	now := time.Now()
+	events := make([]webhook.Event, 0, len(employees))
+	for i, employee := range employees {
+		phone := sql.NullString{String: employee.Phone, Valid: employee.Phone != ""}
+		salary := sql.NullFloat64{Float64: employee.Salary, Valid: employee.Salary > 0}
+		var created models.Employee
+		err := stmt.QueryRowContext(ctx, employee.NIP, employee.Name, employee.Email, phone,
+			employee.Position, employee.Department, salary, employee.HireDate, now).Scan(
+			&created.ID, &created.NIP, &created.Name, &created.Email, &created.Phone, &created.Position,
+			&created.Department, &created.Salary, &created.HireDate, &created.IsActive, &created.CreatedAt, &created.UpdatedAt)
+		if err != nil {
+			if pgErr := apperror.FromPostgres(err); pgErr != nil {
+				return failed(fmt.Errorf("row %d: %s, nothing imported", i+1, pgErr.Detail))
+			}
+			return failed(fmt.Errorf("row %d: %v, nothing imported", i+1, err))
+		}
+		events = append(events, webhook.EmployeeEvent(models.EventEmployeeCreated, created.ToResponse()))
+	}
+
+	if err := webhook.Enqueue(ctx, tx, events...); err != nil {
+		return failed(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return failed(fmt.Errorf("failed to commit import: %v", err))
+	}
+	fmt.Printf("Imported %d employee(s)\n", len(employees))
+	return 0
+}
+
+// readEmployees parses a JSON array or a CSV file with a header row
+func readEmployees(path string) ([]models.CreateEmployeeRequest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var employees []models.CreateEmployeeRequest
+		decoder := json.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&employees); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		return employees, nil
+	case ".csv":
+		return readEmployeesCSV(file)
+	}
+	return nil, fmt.Errorf("unsupported file type %q: use .csv or .json", filepath.Ext(path))
+}
+
+func readEmployeesCSV(r io.Reader) ([]models.CreateEmployeeRequest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for name := range index {
+		if !slices.Contains(employeeColumns, name) {
+			return nil, fmt.Errorf("unknown CSV column %q; expected %s", name, strings.Join(employeeColumns, ", "))
+		}
+	}
+
+	var employees []models.CreateEmployeeRequest
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return employees, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %v", err)
+		}
+
+		field := func(name string) string {
+			if i, ok := index[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		employee := models.CreateEmployeeRequest{
+			NIP:        field("nip"),
+			Name:       field("name"),
+			Email:      field("email"),
+			Phone:      field("phone"),
+			Position:   field("position"),
+			Department: field("department"),
+			HireDate:   field("hire_date"),
+		}
+		if salary := field("salary"); salary != "" {
+			if employee.Salary, err = strconv.ParseFloat(salary, 64); err != nil {
+				return nil, fmt.Errorf("row %d: salary must be a number", row)
+			}
+		}
+		employees = append(employees, employee)
+	}
+}
+
+// validateEmployee applies the same rules as POST /api/v1/employees
+func validateEmployee(employee models.CreateEmployeeRequest) error {
+	var messages []string
+	if err := binding.Validator.ValidateStruct(&employee); err != nil {
+		for _, field := range apperror.InvalidRequest(err).Fields {
+			messages = append(messages, field.Field+" "+field.Message)
+		}
+	}
+	if _, err := time.Parse("2006-01-02", employee.HireDate); err != nil && employee.HireDate != "" {
+		messages = append(messages, "hire_date must use YYYY-MM-DD format")
+	}
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "; "))
+	}
+	return nil
+}
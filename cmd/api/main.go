@@ -1,118 +1,543 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
+	"go-crud-employee/apperror"
+	"go-crud-employee/auth"
 	"go-crud-employee/config"
 	"go-crud-employee/database"
+	"go-crud-employee/events"
 	"go-crud-employee/handlers"
+	"go-crud-employee/health"
+	"go-crud-employee/idempotency"
+	"go-crud-employee/logging"
+	"go-crud-employee/metrics"
 	"go-crud-employee/middleware"
+	"go-crud-employee/models"
+	"go-crud-employee/ratelimit"
+	"go-crud-employee/tlsutil"
+	"go-crud-employee/tracing"
 	"go-crud-employee/utils"
+	"go-crud-employee/webhook"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
+	// Without a command the server starts, as it always has
+	if len(os.Args) < 2 || os.Args[1] == "serve" {
+		serve()
+		return
+	}
+	os.Exit(runCommand(os.Args[1:]))
+}
+
+// serve runs the API server until it receives SIGINT or SIGTERM
+func serve() {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", err)
+	}
+
+	// Switch to structured logging as early as possible
+	if err := logging.Setup(os.Stdout, cfg.Log.Level); err != nil {
+		fatal("Failed to initialize logging", err)
 	}
 
 	// Set Gin mode based on environment
 	if cfg.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+		slog.Debug("Route registered", "method", method, "path", path, "handler", handler)
+	}
+
+	// Initialize tracing before the database so queries are instrumented
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("Failed to initialize tracing", err)
+	}
 
 	// Initialize database connection
 	db, err := database.NewConnection(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", err)
 	}
-	defer db.Close()
 
-	// Create database tables
-	if err := db.CreateTables(); err != nil {
-		log.Fatalf("Failed to create database tables: %v", err)
-	}
+	// Export connection pool statistics
+	metrics.RegisterDB(db.DB, cfg.Database.DBName)
 
-	// Create default admin user
-	if err := db.CreateDefaultUser(); err != nil {
-		log.Printf("Warning: Failed to create default user: %v", err)
+	// Bring the schema up to date, unless deployments run `migrate up` as a
+	// separate step
+	if cfg.Database.AutoMigrate {
+		if _, err := db.MigrateUp(context.Background()); err != nil {
+			fatal("Failed to migrate database", err)
+		}
 	}
 
+	// Create the first admin account when one is configured
+	bootstrapAdmin(context.Background(), cfg, db)
+
 	// Initialize JWT manager
 	jwtManager := utils.NewJWTManager(cfg)
 
+	// Background workers stop before the database pool is closed
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	// Load revoked sessions and keep the cache in sync with other replicas
+	revocations := utils.NewRevocationList()
+	refreshRevocations(db, revocations)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(cfg.JWT.RevocationRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				refreshRevocations(db, revocations)
+			}
+		}
+	}()
+
+	// Initialize rate limiting; the Postgres store shares limits between replicas
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if cfg.RateLimit.Store == "postgres" {
+			store = ratelimit.NewPostgresStore(db)
+		}
+		policies := make(map[string]ratelimit.Policy, len(cfg.RateLimit.Policies))
+		for group, policy := range cfg.RateLimit.Policies {
+			policies[group] = ratelimit.Policy{Rate: policy.Rate, Burst: policy.Burst}
+		}
+		limiter = ratelimit.NewLimiter(store, policies)
+
+		// Drop idle buckets so the store does not grow with every client seen
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-workerCtx.Done():
+					return
+				case <-ticker.C:
+					if err := limiter.Prune(workerCtx); err != nil {
+						slog.Warn("Failed to prune rate limit buckets", "error", err)
+					}
+				}
+			}
+		}()
+	}
+
+	// Idempotency keys live in Postgres so a retry may reach any replica
+	var idempotencyStore *idempotency.Store
+	if cfg.Idempotency.Enabled {
+		idempotencyStore = idempotency.NewStore(db, cfg.Idempotency.TTL, cfg.Idempotency.LockTimeout)
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-workerCtx.Done():
+					return
+				case <-ticker.C:
+					if err := idempotencyStore.Prune(workerCtx); err != nil {
+						slog.Warn("Failed to prune idempotency keys", "error", err)
+					}
+				}
+			}
+		}()
+	}
+
+	// Webhook deliveries are claimed with SKIP LOCKED, so every replica may
+	// run a dispatcher
+	if cfg.Webhook.Enabled {
+		dispatcher := webhook.NewDispatcher(db, cfg.Webhook)
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			dispatcher.Run(workerCtx)
+		}()
+	}
+
+	// The outbox is also the log event streams resume from, so it is pruned
+	// whether or not this replica dispatches webhooks
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				if err := webhook.Prune(workerCtx, db, cfg.Webhook.Retention); err != nil {
+					slog.Warn("Failed to prune outbox events", "error", err)
+				}
+			}
+		}
+	}()
+
+	// Each replica listens for committed events and streams them to its
+	// own clients
+	var broker *events.Broker
+	if cfg.Events.Enabled {
+		broker, err = events.NewBroker(context.Background(), db, cfg.GetDatabaseDSN())
+		if err != nil {
+			fatal("Failed to start event broker", err)
+		}
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			broker.Run(workerCtx)
+		}()
+	}
+
+	// Initialize password authentication backends
+	authenticator, err := auth.NewAuthenticator(cfg, db)
+	if err != nil {
+		fatal("Failed to initialize authentication", err)
+	}
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db, jwtManager)
-	employeeHandler := handlers.NewEmployeeHandler(db)
+	authHandler := handlers.NewAuthHandler(db, jwtManager, revocations, authenticator)
+	employeeHandler := handlers.NewEmployeeHandler(db, cfg.Server.MaxBatchSize)
+	userHandler := handlers.NewUserHandler(db)
+	scimHandler := handlers.NewSCIMHandler(db)
+	webhookHandler := handlers.NewWebhookHandler(db, cfg.Webhook.RequireHTTPS)
+
+	// Readiness checks; the registry also flips to failing on shutdown
+	healthRegistry := health.NewRegistry(cfg.Health.CheckTimeout)
+	healthRegistry.Register("database", health.DatabasePing(db.DB))
+	healthRegistry.Register("migrations", health.Migrations(db.DB, database.LatestMigration()))
+	healthRegistry.Register("db_pool", health.PoolSaturation(db.DB, cfg.Health.PoolDegradedAt))
+	healthHandler := handlers.NewHealthHandler(healthRegistry)
+
+	// Initialize OIDC single sign-on when configured
+	var oidcHandler *handlers.OIDCHandler
+	if cfg.OIDC.Enabled {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		oidcClient, err := auth.NewOIDCClient(ctx, cfg.OIDC)
+		cancel()
+		if err != nil {
+			fatal("Failed to initialize OIDC", err)
+		}
+		oidcHandler = handlers.NewOIDCHandler(db, oidcClient, authHandler)
+	}
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwtManager)
+	authMiddleware := middleware.NewAuthMiddleware(db, jwtManager, revocations, cfg.TLS.ClientAccounts)
+
+	var eventsHandler *handlers.EventsHandler
+	if broker != nil {
+		eventsHandler = handlers.NewEventsHandler(db, broker, authMiddleware, cfg.Events.Heartbeat)
+	}
 
 	// Setup router
-	router := setupRouter(authHandler, employeeHandler, authMiddleware)
+	router := setupRouter(cfg, healthHandler, authHandler, oidcHandler, employeeHandler, eventsHandler, userHandler, scimHandler, webhookHandler, authMiddleware, limiter, idempotencyStore)
+
+	server := &http.Server{
+		Addr:              cfg.GetServerAddress(),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	// Event streams never finish on their own; end them when draining
+	// starts so clients reconnect to another replica
+	if broker != nil {
+		server.RegisterOnShutdown(broker.Close)
+	}
+
+	// Terminate TLS in-process when a certificate is configured; renewed
+	// certificates are picked up without a restart
+	if cfg.TLS.CertFile != "" {
+		reloader, err := tlsutil.NewCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			fatal("Failed to load TLS certificate", err)
+		}
+		if server.TLSConfig, err = tlsutil.ServerConfig(cfg.TLS, reloader); err != nil {
+			fatal("Failed to configure TLS", err)
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			reloader.Watch(workerCtx, cfg.TLS.ReloadInterval)
+		}()
+	}
 
 	// Start server
-	log.Printf("Starting server on %s", cfg.GetServerAddress())
-	log.Printf("Environment: %s", cfg.Env)
-	log.Printf("Default admin credentials - Username: admin, Password: admin123")
+	slog.Info("Starting server", "address", cfg.GetServerAddress(), "env", cfg.Env, "tls", server.TLSConfig != nil)
 
-	// Graceful shutdown
+	serverErr := make(chan error, 2)
 	go func() {
-		if err := router.Run(cfg.GetServerAddress()); err != nil {
-			log.Fatalf("Failed to start server: %v", err)
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
 		}
 	}()
 
+	// Serve metrics on their own listener when configured, so the endpoint
+	// can stay off the public port
+	var metricsServer *http.Server
+	if cfg.Metrics.Address != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:              cfg.Metrics.Address,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		}
+		slog.Info("Serving metrics", "address", cfg.Metrics.Address)
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- err
+			}
+		}()
+	}
+	healthRegistry.SetReady(true)
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
+	shutdownDelay := cfg.Server.ShutdownDelay
+	select {
+	case <-quit:
+	case err := <-serverErr:
+		slog.Error("Server error", "error", err)
+		shutdownDelay = 0
+	}
+
+	// Fail readiness first so load balancers stop routing new requests here
+	slog.Info("Shutting down server")
+	healthRegistry.SetReady(false)
+	time.Sleep(shutdownDelay)
+
+	// Drain in-flight requests
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("Server did not drain cleanly", "error", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			slog.Warn("Metrics server did not stop cleanly", "error", err)
+		}
+	}
+
+	// Stop background workers, then close the database pool
+	stopWorkers()
+	workers.Wait()
+
+	if err := db.Close(); err != nil {
+		slog.Warn("Failed to close database", "error", err)
+	}
+
+	// Flush spans still buffered for export
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+	slog.Info("Server stopped")
 }
 
-func setupRouter(authHandler *handlers.AuthHandler, employeeHandler *handlers.EmployeeHandler, authMiddleware *middleware.AuthMiddleware) *gin.Engine {
-	router := gin.Default()
+// fatal logs a startup error and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// refreshRevocations reloads the revoked-session cache from the database
+func refreshRevocations(db *database.DB, revocations *utils.RevocationList) {
+	revoked, err := db.LoadRevokedSessions()
+	if err != nil {
+		slog.Warn("Failed to refresh revoked sessions", "error", err)
+		return
+	}
+	revocations.Merge(revoked)
+}
+
+// tracedRequest keeps probe and scrape traffic out of traces
+func tracedRequest(r *http.Request) bool {
+	return !strings.HasPrefix(r.URL.Path, "/healthz/") && r.URL.Path != "/health" && r.URL.Path != "/metrics"
+}
+
+func setupRouter(cfg *config.Config, healthHandler *handlers.HealthHandler, authHandler *handlers.AuthHandler, oidcHandler *handlers.OIDCHandler, employeeHandler *handlers.EmployeeHandler, eventsHandler *handlers.EventsHandler, userHandler *handlers.UserHandler, scimHandler *handlers.SCIMHandler, webhookHandler *handlers.WebhookHandler, authMiddleware *middleware.AuthMiddleware, limiter *ratelimit.Limiter, idempotencyStore *idempotency.Store) *gin.Engine {
+	router := gin.New()
+
+	// Only proxies listed in TRUSTED_PROXIES may set the client IP through
+	// X-Forwarded-For; rate limits, sessions and logs rely on it
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("Invalid TRUSTED_PROXIES", err)
+	}
 
-	// Add middleware
-	router.Use(middleware.CORSMiddleware())
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	// Error responses name fields as clients send them
+	apperror.UseJSONFieldNames()
+	middleware.UseProblemDetails(cfg.Server.ErrorFormat == "problem")
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"message": "Employee Management API is running",
-		})
-	})
+	// Add middleware; tracing comes first so the span covers the whole
+	// request, and the request ID must be set before anything logs
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(tracedRequest)))
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.RequestLogger())
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.Recovery())
+	router.Use(middleware.CORSMiddleware(cfg.CORS))
+	router.Use(middleware.SecurityHeaders(cfg.Server.HSTSMaxAge))
+
+	// Rate limits apply per route group; they run after authentication so
+	// clients are counted by user or API key rather than by IP
+	rateLimit := func(group string) gin.HandlerFunc {
+		if limiter == nil {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return middleware.RateLimit(limiter, group)
+	}
+
+	// Idempotency-Key handling also needs the authenticated client, so it
+	// goes on the protected groups that accept POST
+	idempotent := func(c *gin.Context) { c.Next() }
+	if idempotencyStore != nil {
+		idempotent = middleware.Idempotency(idempotencyStore)
+	}
+
+	// Health check endpoints for Kubernetes probes; /health is kept
+	// unchanged for existing monitors
+	router.GET("/healthz/live", healthHandler.Live)
+	router.GET("/healthz/ready", healthHandler.Ready)
+	router.GET("/health", healthHandler.Legacy)
+
+	// Prometheus metrics, unless they are served on a separate address
+	if cfg.Metrics.Address == "" {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
 
 	// API v1 routes
 	v1 := router.Group("/api/v1")
+	v1.Use(middleware.BodyLimit(cfg.Server.MaxBodySize, map[string]int64{
+		"/api/v1/employees/batch": cfg.Server.MaxImportBodySize,
+	}), middleware.RequireJSON())
 	{
 		// Authentication routes (public)
+		// Credential endpoints share the strict "auth" limit per client IP
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/register", authHandler.Register)
-			auth.GET("/profile", authMiddleware.RequireAuth(), authHandler.GetProfile)
+			auth.POST("/login", rateLimit("auth"), authHandler.Login)
+			auth.POST("/register", rateLimit("auth"), authHandler.Register)
+
+			// Single sign-on (only when OIDC is enabled)
+			if oidcHandler != nil {
+				auth.GET("/oidc/login", rateLimit("auth"), oidcHandler.Login)
+				auth.GET("/oidc/callback", rateLimit("auth"), oidcHandler.Callback)
+			}
+		}
+
+		// Account routes of the signed-in user
+		account := v1.Group("/auth")
+		account.Use(authMiddleware.RequireAuthAllowPasswordReset(), rateLimit("account"))
+		{
+			account.GET("/profile", authHandler.GetProfile)
+			account.POST("/change-password", authHandler.ChangePassword)
+			account.GET("/sessions", authHandler.GetSessions)
+			account.DELETE("/sessions/:id", authHandler.RevokeSession)
 		}
 
-		// Employee routes (protected)
+		// Employee routes (protected); responses carry salaries, so they
+		// must not be cached
 		employees := v1.Group("/employees")
-		employees.Use(authMiddleware.RequireAuth())
+		employees.Use(authMiddleware.RequireAuth(), rateLimit("employees"), middleware.NoStore(), idempotent)
 		{
 			employees.POST("/", employeeHandler.CreateEmployee)
+			employees.POST("/batch", employeeHandler.BatchEmployees)
 			employees.GET("/", employeeHandler.GetEmployees)
 			employees.GET("/:id", employeeHandler.GetEmployee)
 			employees.PUT("/:id", employeeHandler.UpdateEmployee)
 			employees.DELETE("/:id", employeeHandler.DeleteEmployee)
+
+			// Server-Sent Events stream of changes (only when enabled)
+			if eventsHandler != nil {
+				employees.GET("/events", eventsHandler.StreamEmployeeEvents)
+			}
 		}
+
+		// User administration routes (admin only)
+		users := v1.Group("/users")
+		users.Use(authMiddleware.RequireAuth(), authMiddleware.RequireRole(models.RoleAdmin), rateLimit("users"), idempotent)
+		{
+			users.POST("/", userHandler.CreateUser)
+			users.GET("/", userHandler.GetUsers)
+			users.GET("/:id", userHandler.GetUser)
+			users.PUT("/:id", userHandler.UpdateUser)
+			users.PUT("/:id/status", userHandler.UpdateUserStatus)
+			users.POST("/:id/reset-password", userHandler.ResetUserPassword)
+			users.DELETE("/:id", userHandler.DeleteUser)
+		}
+
+		// Webhook subscriptions and their delivery log (admin only)
+		webhooks := v1.Group("/webhooks")
+		webhooks.Use(authMiddleware.RequireAuth(), authMiddleware.RequireRole(models.RoleAdmin), rateLimit("webhooks"), middleware.NoStore(), idempotent)
+		{
+			webhooks.POST("/", webhookHandler.CreateWebhook)
+			webhooks.GET("/", webhookHandler.GetWebhooks)
+			webhooks.GET("/:id", webhookHandler.GetWebhook)
+			webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+			webhooks.GET("/:id/deliveries/:deliveryId", webhookHandler.GetDelivery)
+			webhooks.POST("/:id/deliveries/:deliveryId/retry", webhookHandler.RetryDelivery)
+		}
+	}
+
+	// SCIM 2.0 provisioning (only when a client token is configured)
+	if cfg.SCIM.Token != "" {
+		scim := router.Group("/scim/v2")
+		scim.Use(middleware.SCIMAuth(cfg.SCIM.Token), rateLimit("scim"))
+		scim.Use(middleware.BodyLimit(cfg.Server.MaxBodySize, nil), middleware.RequireJSON("application/scim+json", "application/json"))
+		{
+			scim.GET("/ServiceProviderConfig", scimHandler.ServiceProviderConfig)
+			scim.GET("/ResourceTypes", scimHandler.ResourceTypes)
+			scim.GET("/Users", scimHandler.GetUsers)
+			scim.POST("/Users", scimHandler.CreateUser)
+			scim.GET("/Users/:id", scimHandler.GetUser)
+			scim.PUT("/Users/:id", scimHandler.ReplaceUser)
+			scim.PATCH("/Users/:id", scimHandler.PatchUser)
+			scim.DELETE("/Users/:id", scimHandler.DeleteUser)
+		}
+	}
+
+	// OpenAPI document of the routes above, and Swagger UI to browse it
+	if cfg.Server.APIDocs {
+		registerAPIDocs(router)
 	}
 
 	return router
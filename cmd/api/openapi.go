@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"go-crud-employee/config"
+	"go-crud-employee/handlers"
+	"go-crud-employee/health"
+	"go-crud-employee/middleware"
+	"go-crud-employee/models"
+	"go-crud-employee/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+var apiInfo = openapi.Info{
+	Title:   "Employee API",
+	Version: "1.0.0",
+	Description: "Employee and user administration API. Responses use the APIResponse envelope; " +
+		"errors are application/problem+json when requested with Accept or configured with ERROR_FORMAT.",
+}
+
+// LiveStatus is the body of the liveness probe
+type LiveStatus struct {
+	Status health.Status `json:"status"`
+}
+
+// LegacyHealthStatus is the body of /health
+type LegacyHealthStatus struct {
+	Status  string `json:"status"` // ok, or shutting_down with 503
+	Message string `json:"message"`
+}
+
+// scimListQuery is the query string of the SCIM user list
+type scimListQuery struct {
+	Filter     string `form:"filter"` // e.g. userName eq "jdoe"
+	StartIndex int    `form:"startIndex" binding:"omitempty,min=1"`
+	Count      int    `form:"count" binding:"omitempty,min=0,max=200"`
+}
+
+// oidcCallbackQuery is the query string the identity provider redirects with
+type oidcCallbackQuery struct {
+	State            string `form:"state"`
+	Code             string `form:"code"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+// apiDocs documents every route of setupRouter, keyed by method and path.
+// Request and response bodies are described by the models they bind to;
+// `api openapi --check` fails when a route is missing here.
+var apiDocs = map[string]openapi.Doc{
+	// Health and metrics
+	"GET /healthz/live": {
+		Tag: "Health", Summary: "Liveness probe",
+		Raw: true, Response: LiveStatus{},
+	},
+	"GET /healthz/ready": {
+		Tag: "Health", Summary: "Readiness probe",
+		Description: "Returns 503 with the same body when a required dependency is down.",
+		Raw:         true, Response: health.Report{},
+	},
+	"GET /health": {
+		Tag: "Health", Summary: "Legacy health check",
+		Description: "Returns 503 with status shutting_down while the server does not accept traffic; checks no dependencies.",
+		Raw:         true, Response: LegacyHealthStatus{},
+	},
+	"GET /metrics": {
+		Tag: "Health", Summary: "Prometheus metrics",
+		Raw: true, ContentType: "text/plain",
+	},
+
+	// Authentication
+	"POST /api/v1/auth/login": {
+		Tag: "Auth", Summary: "Log in with username and password",
+		Body: models.LoginRequest{}, Response: models.LoginResponse{},
+	},
+	"POST /api/v1/auth/register": {
+		Tag: "Auth", Summary: "Register a user account",
+		Body: models.RegisterRequest{}, Response: models.UserInfo{}, Status: http.StatusCreated,
+	},
+	"GET /api/v1/auth/oidc/login": {
+		Tag: "Auth", Summary: "Start single sign-on",
+		Description: "Redirects to the identity provider.",
+		Raw:         true, Status: http.StatusFound,
+	},
+	"GET /api/v1/auth/oidc/callback": {
+		Tag: "Auth", Summary: "Finish single sign-on",
+		Query: oidcCallbackQuery{}, Response: models.LoginResponse{},
+	},
+	"GET /api/v1/auth/profile": {
+		Tag: "Account", Summary: "Get the signed-in user", Security: openapi.BearerAuth,
+		Response: models.UserInfo{},
+	},
+	"POST /api/v1/auth/change-password": {
+		Tag: "Account", Summary: "Change the password", Security: openapi.BearerAuth,
+		Body: models.ChangePasswordRequest{},
+	},
+	"GET /api/v1/auth/sessions": {
+		Tag: "Account", Summary: "List active sessions", Security: openapi.BearerAuth,
+		Response: []models.SessionResponse{},
+	},
+	"DELETE /api/v1/auth/sessions/:id": {
+		Tag: "Account", Summary: "Revoke a session", Security: openapi.BearerAuth,
+		StringParams: []string{"id"},
+	},
+
+	// Employees
+	"POST /api/v1/employees/": {
+		Tag: "Employees", Summary: "Create an employee", Security: openapi.BearerAuth,
+		Body: models.CreateEmployeeRequest{}, Response: models.EmployeeResponse{}, Status: http.StatusCreated,
+	},
+	"POST /api/v1/employees/batch": {
+		Tag: "Employees", Summary: "Create, update or deactivate employees in one request", Security: openapi.BearerAuth,
+		Description: "Best-effort batches that applied some operations return 207; batches that applied none, " +
+			"atomic ones with a failure or best-effort ones where every operation failed, return 422 with the results in data.",
+		Body: models.BatchEmployeeRequest{}, Response: models.BatchEmployeeResponse{},
+	},
+	"GET /api/v1/employees/": {
+		Tag: "Employees", Summary: "List employees", Security: openapi.BearerAuth,
+		Query: models.EmployeeFilter{}, Response: models.EmployeeListResponse{},
+	},
+	"GET /api/v1/employees/:id": {
+		Tag: "Employees", Summary: "Get an employee", Security: openapi.BearerAuth,
+		Response: models.EmployeeResponse{},
+	},
+	"PUT /api/v1/employees/:id": {
+		Tag: "Employees", Summary: "Update an employee", Security: openapi.BearerAuth,
+		Body: models.UpdateEmployeeRequest{}, Response: models.EmployeeResponse{},
+	},
+	"DELETE /api/v1/employees/:id": {
+		Tag: "Employees", Summary: "Deactivate an employee", Security: openapi.BearerAuth,
+	},
+	"GET /api/v1/employees/events": {
+		Tag: "Employees", Summary: "Stream employee changes", Security: openapi.BearerAuth,
+		Description: "Server-Sent Events named employee.created, employee.updated and employee.deactivated. " +
+			"Reconnect with Last-Event-ID to receive missed events; a reset event means they were pruned.",
+		Raw: true, ContentType: "text/event-stream",
+	},
+
+	// Users
+	"POST /api/v1/users/": {
+		Tag: "Users", Summary: "Create a user", Security: openapi.BearerAuth,
+		Body: models.CreateUserRequest{}, Response: models.UserResponse{}, Status: http.StatusCreated,
+	},
+	"GET /api/v1/users/": {
+		Tag: "Users", Summary: "List users", Security: openapi.BearerAuth,
+		Query: models.UserFilter{}, Response: models.UserListResponse{},
+	},
+	"GET /api/v1/users/:id": {
+		Tag: "Users", Summary: "Get a user", Security: openapi.BearerAuth,
+		Response: models.UserResponse{},
+	},
+	"PUT /api/v1/users/:id": {
+		Tag: "Users", Summary: "Update a user", Security: openapi.BearerAuth,
+		Body: models.UpdateUserRequest{}, Response: models.UserResponse{},
+	},
+	"PUT /api/v1/users/:id/status": {
+		Tag: "Users", Summary: "Activate or deactivate a user", Security: openapi.BearerAuth,
+		Body: models.UserStatusRequest{}, Response: models.UserResponse{},
+	},
+	"POST /api/v1/users/:id/reset-password": {
+		Tag: "Users", Summary: "Reset a user's password", Security: openapi.BearerAuth,
+		Body: models.ResetPasswordRequest{}, Response: models.ResetPasswordResponse{},
+	},
+	"DELETE /api/v1/users/:id": {
+		Tag: "Users", Summary: "Delete a user", Security: openapi.BearerAuth,
+	},
+
+	// Webhooks
+	"POST /api/v1/webhooks/": {
+		Tag: "Webhooks", Summary: "Subscribe to employee events", Security: openapi.BearerAuth,
+		Description: "The signing secret is only returned here and when it is changed.",
+		Body:        models.CreateWebhookRequest{}, Response: models.WebhookResponse{}, Status: http.StatusCreated,
+	},
+	"GET /api/v1/webhooks/": {
+		Tag: "Webhooks", Summary: "List webhook subscriptions", Security: openapi.BearerAuth,
+		Response: []models.WebhookResponse{},
+	},
+	"GET /api/v1/webhooks/:id": {
+		Tag: "Webhooks", Summary: "Get a webhook subscription", Security: openapi.BearerAuth,
+		Response: models.WebhookResponse{},
+	},
+	"PUT /api/v1/webhooks/:id": {
+		Tag: "Webhooks", Summary: "Update a webhook subscription", Security: openapi.BearerAuth,
+		Body: models.UpdateWebhookRequest{}, Response: models.WebhookResponse{},
+	},
+	"DELETE /api/v1/webhooks/:id": {
+		Tag: "Webhooks", Summary: "Delete a webhook subscription", Security: openapi.BearerAuth,
+	},
+	"GET /api/v1/webhooks/:id/deliveries": {
+		Tag: "Webhooks", Summary: "List deliveries", Security: openapi.BearerAuth,
+		Query: models.WebhookDeliveryFilter{}, Response: models.WebhookDeliveryListResponse{},
+	},
+	"GET /api/v1/webhooks/:id/deliveries/:deliveryId": {
+		Tag: "Webhooks", Summary: "Get a delivery with its attempt log", Security: openapi.BearerAuth,
+		Response: models.WebhookDeliveryResponse{},
+	},
+	"POST /api/v1/webhooks/:id/deliveries/:deliveryId/retry": {
+		Tag: "Webhooks", Summary: "Retry a dead delivery", Security: openapi.BearerAuth,
+		Response: models.WebhookDeliveryResponse{},
+	},
+
+	// SCIM 2.0 provisioning
+	"GET /scim/v2/ServiceProviderConfig": {
+		Tag: "SCIM", Summary: "SCIM service provider configuration", Security: openapi.SCIMAuth,
+		SCIM: true, Raw: true, Response: gin.H{},
+	},
+	"GET /scim/v2/ResourceTypes": {
+		Tag: "SCIM", Summary: "SCIM resource types", Security: openapi.SCIMAuth,
+		SCIM: true, Raw: true, Response: gin.H{},
+	},
+	"GET /scim/v2/Users": {
+		Tag: "SCIM", Summary: "List users", Security: openapi.SCIMAuth,
+		SCIM: true, Raw: true, Query: scimListQuery{}, Response: models.SCIMListResponse{},
+	},
+	"POST /scim/v2/Users": {
+		Tag: "SCIM", Summary: "Provision a user", Security: openapi.SCIMAuth,
+		SCIM: true, Raw: true, Body: models.SCIMUser{}, Response: models.SCIMUser{}, Status: http.StatusCreated,
+	},
+	"GET /scim/v2/Users/:id": {
+		Tag: "SCIM", Summary: "Get a user", Security: openapi.SCIMAuth,
+		SCIM: true, Raw: true, Response: models.SCIMUser{},
+	},
+	"PUT /scim/v2/Users/:id": {
+		Tag: "SCIM", Summary: "Replace a user", Security: openapi.SCIMAuth,
+		SCIM: true, Raw: true, Body: models.SCIMUser{}, Response: models.SCIMUser{},
+	},
+	"PATCH /scim/v2/Users/:id": {
+		Tag: "SCIM", Summary: "Modify a user", Security: openapi.SCIMAuth,
+		SCIM: true, Raw: true, Body: models.SCIMPatchRequest{}, Response: models.SCIMUser{},
+	},
+	"DELETE /scim/v2/Users/:id": {
+		Tag: "SCIM", Summary: "Deprovision a user", Security: openapi.SCIMAuth,
+		SCIM: true, Raw: true, Status: http.StatusNoContent,
+	},
+}
+
+// registerAPIDocs serves the OpenAPI document of the routes registered so
+// far at /openapi.json and Swagger UI at /docs/
+func registerAPIDocs(router *gin.Engine) {
+	routes := router.Routes()
+	if undocumented, _ := openapi.Check(routes, apiDocs); len(undocumented) > 0 {
+		slog.Warn("Routes missing from the OpenAPI document", "routes", undocumented)
+	}
+
+	docsHandler, err := handlers.NewDocsHandler(openapi.Generate(apiInfo, routes, apiDocs))
+	if err != nil {
+		slog.Error("Failed to build the OpenAPI document", "error", err)
+		return
+	}
+	router.GET("/openapi.json", docsHandler.GetSpec)
+	router.GET("/docs/*filepath", docsHandler.ServeUI)
+}
+
+// bareRouter builds the router without dependencies. Only middleware that
+// runs before the handlers works; the handlers themselves must not be called.
+func bareRouter(cfg *config.Config) *gin.Engine {
+	return setupRouter(cfg, &handlers.HealthHandler{}, &handlers.AuthHandler{}, &handlers.OIDCHandler{},
+		&handlers.EmployeeHandler{}, &handlers.EventsHandler{}, &handlers.UserHandler{}, &handlers.SCIMHandler{},
+		&handlers.WebhookHandler{}, &middleware.AuthMiddleware{}, nil, nil)
+}
+
+// openapiCommand prints the OpenAPI document, or with --check fails when
+// the route table and apiDocs disagree. The router is built with every
+// optional route enabled; handlers are never called.
+func openapiCommand(args []string) int {
+	flags := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	check := flags.Bool("check", false, "fail if a route is undocumented or a documented route does not exist")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	cfg := &config.Config{}
+	cfg.SCIM.Token = "openapi"
+	routes := bareRouter(cfg).Routes()
+
+	if *check {
+		undocumented, unknown := openapi.Check(routes, apiDocs)
+		for _, route := range undocumented {
+			fmt.Fprintf(os.Stderr, "undocumented route: %s\n", route)
+		}
+		for _, route := range unknown {
+			fmt.Fprintf(os.Stderr, "documented route does not exist: %s\n", route)
+		}
+		if len(undocumented) > 0 || len(unknown) > 0 {
+			return 1
+		}
+		fmt.Printf("All %d routes are documented\n", len(routes))
+		return 0
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(openapi.Generate(apiInfo, routes, apiDocs)); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write the OpenAPI document: %v\n", err)
+		return 1
+	}
+	return 0
+}
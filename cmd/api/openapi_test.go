@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go-crud-employee/config"
+	"go-crud-employee/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPICoversRoutes fails when a route is added without documenting
+// it in apiDocs, or a documented route is removed
+func TestOpenAPICoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.SCIM.Token = "openapi"
+	routes := bareRouter(cfg).Routes()
+
+	doc := openapi.Generate(apiInfo, routes, apiDocs)
+	for _, route := range routes {
+		path := route.Path
+		for _, segment := range strings.Split(path, "/") {
+			if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+				path = strings.Replace(path, segment, "{"+segment[1:]+"}", 1)
+			}
+		}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("%s %s: path %s missing from the document", route.Method, route.Path, path)
+			continue
+		}
+		if (*item)[strings.ToLower(route.Method)] == nil {
+			t.Errorf("%s %s: operation missing from the document", route.Method, route.Path)
+		}
+	}
+
+	if undocumented, unknown := openapi.Check(routes, apiDocs); len(undocumented) > 0 || len(unknown) > 0 {
+		t.Errorf("undocumented routes %v, documented routes that do not exist %v", undocumented, unknown)
+	}
+}
+
+// TestOpenAPIDocument checks the document is valid JSON with unique
+// operation IDs and resolvable schema references
+func TestOpenAPIDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.SCIM.Token = "openapi"
+	doc := openapi.Generate(apiInfo, bareRouter(cfg).Routes(), apiDocs)
+
+	ids := make(map[string]string)
+	for path, item := range doc.Paths {
+		for method, op := range *item {
+			if other, ok := ids[op.OperationID]; ok {
+				t.Errorf("operationId %s used by %s %s and %s", op.OperationID, method, path, other)
+			}
+			ids[op.OperationID] = method + " " + path
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range strings.Split(string(data), `"$ref":"#/components/schemas/`)[1:] {
+		name, _, _ := strings.Cut(ref, `"`)
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("reference to undefined schema %s", name)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"go-crud-employee/database"
+	"go-crud-employee/seed"
+	"go-crud-employee/utils"
+)
+
+// seedCommand fills the database with generated employees and users
+func seedCommand(args []string) int {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	employees := flags.Int("employees", 1000, "number of employees to add")
+	users := flags.Int("users", 100, "number of users to add")
+	seedValue := flags.Uint64("seed", 1, "random seed; the same seed yields the same rows")
+	batchSize := flags.Int("batch-size", 10000, "rows per COPY transaction")
+	password := flags.String("password", "password123", "password of every seeded user")
+	force := flags.Bool("force", false, "allow seeding when ENV=production")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 || *employees < 0 || *users < 0 || *batchSize < 1 {
+		fmt.Fprintln(os.Stderr, "usage: api seed [--employees n] [--users n] [--seed s] [--batch-size n] [--password p]")
+		return 2
+	}
+	if !utils.IsValidPassword(*password) {
+		return failed(errors.New("password must be at least 6 characters long"))
+	}
+
+	cfg, db, err := openDB()
+	if err != nil {
+		return failed(err)
+	}
+	defer db.Close()
+	if cfg.Env == "production" && !*force {
+		return failed(errors.New("refusing to seed a production database; pass --force to do it anyway"))
+	}
+
+	ctx := context.Background()
+	generator := seed.NewGenerator(*seedValue)
+
+	// Numbering continues after earlier runs, so seeding again adds rows
+	// instead of failing on duplicate NIPs, usernames and emails
+	lastEmployee, err := lastSeeded(ctx, db, `SELECT COALESCE(MAX(SUBSTRING(nip FROM 4)::int), 0) FROM employees
+		WHERE email LIKE '%@' || $1 AND nip ~ '^EMP[0-9]{8}$'`)
+	if err != nil {
+		return failed(err)
+	}
+	lastUser, err := lastSeeded(ctx, db, `SELECT COALESCE(MAX(SUBSTRING(username FROM '[0-9]+$')::int), 0) FROM users
+		WHERE email LIKE '%@' || $1 AND username ~ '[0-9]+$'`)
+	if err != nil {
+		return failed(err)
+	}
+
+	// Test data bypasses the webhook outbox; subscribers hear nothing of it
+	err = copyGenerated(ctx, db, "employees", seed.EmployeeColumns, lastEmployee, *employees, *batchSize, generator.Employee)
+	if err != nil {
+		return failed(err)
+	}
+
+	// Hashed once: bcrypt per row would take longer than the COPY itself
+	passwordHash, err := utils.HashPassword(*password)
+	if err != nil {
+		return failed(err)
+	}
+	err = copyGenerated(ctx, db, "users", seed.UserColumns, lastUser, *users, *batchSize, func(n int) []any {
+		return generator.User(n, passwordHash)
+	})
+	if err != nil {
+		return failed(err)
+	}
+	return 0
+}
+
+// lastSeeded returns the highest row number an earlier seed run used
+func lastSeeded(ctx context.Context, db *database.DB, query string) (int, error) {
+	var last int
+	if err := db.QueryRowContext(ctx, query, seed.EmailDomain).Scan(&last); err != nil {
+		return 0, fmt.Errorf("failed to find earlier seed rows: %v", err)
+	}
+	return last, nil
+}
+
+// copyGenerated copies count generated rows numbered after last into table,
+// one transaction per batch so memory stays flat for millions of rows
+func copyGenerated(ctx context.Context, db *database.DB, table string, columns []string, last, count, batchSize int, row func(n int) []any) error {
+	if count == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	batch := make([][]any, 0, min(batchSize, count))
+	for n := last + 1; n <= last+count; n++ {
+		batch = append(batch, row(n))
+		if len(batch) == cap(batch) || n == last+count {
+			if err := db.CopyRows(ctx, table, columns, batch); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "%s: %d/%d\n", table, n-last, count)
+			batch = batch[:0]
+		}
+	}
+
+	elapsed := time.Since(start)
+	fmt.Printf("Seeded %d %s in %s (%.0f rows/s)\n", count, table, elapsed.Round(time.Millisecond), float64(count)/elapsed.Seconds())
+	return nil
+}
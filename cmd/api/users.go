@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"go-crud-employee/config"
+	"go-crud-employee/database"
+	"go-crud-employee/models"
+	"go-crud-employee/utils"
+
+	"golang.org/x/term"
+)
+
+// legacyAdminPassword is the password older releases gave the admin account
+const legacyAdminPassword = "admin123"
+
+// bootstrapAdmin creates the first admin from BOOTSTRAP_ADMIN_PASSWORD. Without
+// a password, an empty database has no admin until `api user create` is run.
+func bootstrapAdmin(ctx context.Context, cfg *config.Config, db *database.DB) {
+	if password := cfg.Bootstrap.AdminPassword; password != "" {
+		if !utils.IsValidPassword(password) {
+			fatal("Invalid BOOTSTRAP_ADMIN_PASSWORD", errors.New("password must be at least 6 characters long"))
+		}
+		hashedPassword, err := utils.HashPassword(password)
+		if err != nil {
+			fatal("Failed to hash admin password", err)
+		}
+		created, err := db.BootstrapAdmin(ctx, cfg.Bootstrap.AdminUsername, cfg.Bootstrap.AdminEmail, hashedPassword)
+		if err != nil {
+			fatal("Failed to create admin user", err)
+		}
+		if created {
+			slog.Info("Admin user created", "username", cfg.Bootstrap.AdminUsername)
+		}
+	}
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE role = $1)", models.RoleAdmin).Scan(&exists); err != nil {
+		slog.Warn("Failed to check for an admin user", "error", err)
+		return
+	}
+	if !exists {
+		slog.Warn("No admin user exists; set BOOTSTRAP_ADMIN_PASSWORD or run: api user create --role admin <username> <email>")
+		return
+	}
+
+	// Databases created by older releases may still use the published password
+	var passwordHash string
+	err := db.QueryRowContext(ctx, "SELECT password_hash FROM users WHERE username = $1 AND is_active = true", "admin").Scan(&passwordHash)
+	if err == nil && utils.CheckPassword(legacyAdminPassword, passwordHash) == nil {
+		slog.Warn("The admin account still uses the default password; change it with: api user set-password admin")
+	}
+}
+
+// userCommand manages accounts without going through the API
+func userCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "create":
+		return createUserCommand(args[1:])
+	case "set-password":
+		return setPasswordCommand(args[1:])
+	case "disable":
+		return disableUserCommand(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "unknown user command %q\n", args[0])
+	return 2
+}
+
+func createUserCommand(args []string) int {
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+	role := flags.String("role", models.RoleUser, "role of the new user (admin or user)")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from stdin")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: api user create [--role r] [--password-stdin] <username> <email>")
+		return 2
+	}
+	if *role != models.RoleAdmin && *role != models.RoleUser {
+		fmt.Fprintln(os.Stderr, "role must be admin or user")
+		return 2
+	}
+
+	hashedPassword, err := readPasswordHash(*passwordStdin)
+	if err != nil {
+		return failed(err)
+	}
+
+	_, db, err := openDB()
+	if err != nil {
+		return failed(err)
+	}
+	defer db.Close()
+
+	var id int
+	now := time.Now()
+	err = db.QueryRowContext(context.Background(),
+		`INSERT INTO users (username, email, password_hash, role, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		flags.Arg(0), flags.Arg(1), hashedPassword, *role, now, now,
+	).Scan(&id)
+	if err != nil {
+		return failed(fmt.Errorf("failed to create user: %v", err))
+	}
+
+	fmt.Printf("Created %s user %s (id %d)\n", *role, flags.Arg(0), id)
+	return 0
+}
+
+func setPasswordCommand(args []string) int {
+	flags := flag.NewFlagSet("user set-password", flag.ContinueOnError)
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from stdin")
+	requireReset := flags.Bool("require-reset", false, "make the user change the password on next login")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: api user set-password [--password-stdin] [--require-reset] <username>")
+		return 2
+	}
+
+	hashedPassword, err := readPasswordHash(*passwordStdin)
+	if err != nil {
+		return failed(err)
+	}
+
+	_, db, err := openDB()
+	if err != nil {
+		return failed(err)
+	}
+	defer db.Close()
+
+	return updateUser(db, flags.Arg(0), "Password changed",
+		"UPDATE users SET password_hash = $2, password_reset_required = $3, updated_at = $4 WHERE username = $1",
+		hashedPassword, *requireReset, time.Now())
+}
+
+func disableUserCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: api user disable <username>")
+		return 2
+	}
+
+	_, db, err := openDB()
+	if err != nil {
+		return failed(err)
+	}
+	defer db.Close()
+
+	// Requests check is_active on every call, so existing tokens stop
+	// working immediately
+	return updateUser(db, args[0], "Disabled",
+		"UPDATE users SET is_active = false, updated_at = $2 WHERE username = $1",
+		time.Now())
+}
+
+// updateUser runs query with the username as $1 and reports the outcome
+func updateUser(db *database.DB, username, done, query string, args ...any) int {
+	result, err := db.ExecContext(context.Background(), query, append([]any{username}, args...)...)
+	if err != nil {
+		return failed(fmt.Errorf("failed to update user: %v", err))
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return failed(fmt.Errorf("user %s not found", username))
+	}
+
+	fmt.Printf("%s: %s\n", done, username)
+	return 0
+}
+
+// readPasswordHash reads a new password from stdin or, on a terminal, from a
+// prompt without echo, and returns its hash
+func readPasswordHash(fromStdin bool) (string, error) {
+	var password string
+	switch {
+	case fromStdin:
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read password: %v", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	case term.IsTerminal(int(os.Stdin.Fd())):
+		first, err := prompt("Password: ")
+		if err != nil {
+			return "", err
+		}
+		second, err := prompt("Repeat password: ")
+		if err != nil {
+			return "", err
+		}
+		if first != second {
+			return "", errors.New("passwords do not match")
+		}
+		password = first
+	default:
+		return "", errors.New("stdin is not a terminal; pass the password with --password-stdin")
+	}
+
+	if !utils.IsValidPassword(password) {
+		return "", errors.New("password must be at least 6 characters long")
+	}
+	return utils.HashPassword(password)
+}
+
+func prompt(label string) (string, error) {
+	fmt.Fprint(os.Stderr, label)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %v", err)
+	}
+	return string(password), nil
+}
@@ -1,22 +1,50 @@
 package config
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Server   ServerConfig
-	Env      string
+	Database    DatabaseConfig
+	JWT         JWTConfig
+	Server      ServerConfig
+	OIDC        OIDCConfig
+	Auth        AuthConfig
+	LDAP        LDAPConfig
+	SCIM        SCIMConfig
+	Health      HealthConfig
+	Metrics     MetricsConfig
+	Tracing     TracingConfig
+	Log         LogConfig
+	RateLimit   RateLimitConfig
+	Idempotency IdempotencyConfig
+	Webhook     WebhookConfig
+	Events      EventsConfig
+	CORS        CORSConfig
+	TLS         TLSConfig
+	Bootstrap   BootstrapConfig
+	Env         string
+
+	settings []Setting // Resolved values and their sources, for Settings
 }
 
+// Development defaults that Validate refuses in production
+const (
+	defaultDBPassword = "password"
+	defaultJWTSecret  = "default-secret-key"
+)
+
 type DatabaseConfig struct {
 	Host     string
 	Port     int
@@ -24,78 +52,535 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	// Enforced by Postgres on every statement; 0 disables it
+	StatementTimeout time.Duration
+	MaxOpenConns     int
+	MaxIdleConns     int
+	ConnMaxLifetime  time.Duration // 0 keeps connections open indefinitely
+	ConnMaxIdleTime  time.Duration
+	AutoMigrate      bool // Apply pending migrations when the server starts
+}
+
+// BootstrapConfig describes the first admin account, created on startup
+// when no admin exists and a password is configured
+type BootstrapConfig struct {
+	AdminUsername string
+	AdminEmail    string
+	AdminPassword string // Empty skips creation; use BOOTSTRAP_ADMIN_PASSWORD_FILE for secrets
 }
 
 type JWTConfig struct {
-	Secret string
-	Expiry time.Duration
+	Secret            string
+	Expiry            time.Duration
+	RevocationRefresh time.Duration // How often the revoked-session cache is reloaded
 }
 
 type ServerConfig struct {
-	Host string
-	Port string
+	Host              string
+	Port              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownDelay     time.Duration // Time between failing readiness and draining
+	ShutdownTimeout   time.Duration // Deadline for in-flight requests to finish
+	ErrorFormat       string        // "json" or "problem" (RFC 7807 for every client)
+	MaxBodySize       int64         // Request body limit in bytes
+	MaxImportBodySize int64         // Body limit of bulk import endpoints
+	MaxBatchSize      int           // Operations allowed in one batch request
+	HSTSMaxAge        time.Duration // Strict-Transport-Security max-age; 0 omits the header
+	APIDocs           bool          // Serve /openapi.json and Swagger UI at /docs/
+	TrustedProxies    []string      // IPs or CIDRs whose X-Forwarded-For is believed; empty trusts none
+}
+
+// HealthConfig tunes the readiness checks
+type HealthConfig struct {
+	CheckTimeout   time.Duration
+	PoolDegradedAt float64 // Share of connections in use that reports degraded
+}
+
+// MetricsConfig configures the Prometheus endpoint
+type MetricsConfig struct {
+	Address string // Separate listen address for /metrics; empty serves it on the API port
+}
+
+// LogConfig configures structured logging
+type LogConfig struct {
+	Level string // "debug", "info", "warn" or "error"
+}
+
+// TLSConfig configures TLS termination by the server itself. An empty
+// CertFile serves plain HTTP.
+type TLSConfig struct {
+	CertFile       string
+	KeyFile        string
+	ReloadInterval time.Duration     // How often the files are checked for renewal
+	MinVersion     string            // "1.2" or "1.3"
+	CipherSuites   []string          // TLS 1.2 suites by standard name; empty uses Go's defaults
+	ClientCAFile   string            // CA bundle that client certificates must chain to
+	ClientAuth     string            // "none", "optional" or "require"
+	ClientAccounts map[string]string // Client certificate CN -> service account username
+}
+
+// CORSConfig configures which browser origins may call the API
+type CORSConfig struct {
+	AllowedOrigins   []string // Exact origins or "https://*.example.com" for any subdomain; "*" allows all without credentials
+	AllowCredentials bool
+	ExposedHeaders   []string // Response headers readable by browser scripts
+	MaxAge           time.Duration
+}
+
+// RateLimitConfig configures request rate limiting
+type RateLimitConfig struct {
+	Enabled  bool
+	Store    string                     // "memory" (per replica) or "postgres" (shared by all replicas)
+	Policies map[string]RateLimitPolicy // Route group -> policy; "default" applies to groups without one
+}
+
+// RateLimitPolicy is a token bucket holding Burst requests, refilled at Rate
+// requests per second
+type RateLimitPolicy struct {
+	Rate  float64
+	Burst int
+}
+
+// IdempotencyConfig configures Idempotency-Key handling of POST requests
+type IdempotencyConfig struct {
+	Enabled     bool
+	TTL         time.Duration // How long responses are kept for replay
+	LockTimeout time.Duration // After this, a retry may take over a key whose request never finished
+}
+
+// WebhookConfig configures delivery of employee events to webhook
+// subscriptions
+type WebhookConfig struct {
+	Enabled         bool          // Run the dispatcher on this replica; replicas share the work
+	PollInterval    time.Duration // How often the outbox and due retries are checked
+	Timeout         time.Duration // Per delivery attempt
+	MaxAttempts     int           // After this many failures a delivery is dead
+	RetryBackoff    time.Duration // Delay before the first retry, doubled on each retry
+	RetryBackoffMax time.Duration
+	Concurrency     int           // Deliveries sent in parallel
+	Retention       time.Duration // Finished events and their deliveries are kept this long
+	RequireHTTPS    bool          // Reject subscription URLs that are not https
 }
 
+// EventsConfig configures the Server-Sent Events stream of employee changes
+type EventsConfig struct {
+	Enabled   bool
+	Heartbeat time.Duration // Keeps idle streams open through proxies; access is rechecked at the same pace
+}
+
+// TracingConfig configures OpenTelemetry tracing
+type TracingConfig struct {
+	Exporter     string // "none", "otlp" or "stdout"
+	OTLPEndpoint string // host:port of the OTLP/HTTP collector
+	OTLPInsecure bool   // Send to the collector over plain HTTP
+	SampleRatio  float64
+	ServiceName  string
+}
+
+// AuthConfig selects the password authentication backends used by Login
+type AuthConfig struct {
+	Backends []string // Tried in order: "local", "ldap"
+}
+
+// LDAPConfig configures authentication against an LDAP / Active Directory server
+type LDAPConfig struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string // Service account used to look users up
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // Must contain one %s for the escaped username
+	EmailAttribute     string
+	GroupAttribute     string
+	GroupRoles         map[string]string // Group CN -> local role
+	Timeout            time.Duration
+}
+
+// SCIMConfig configures the SCIM 2.0 provisioning endpoint
+type SCIMConfig struct {
+	Token string // Bearer token of the provisioning client; empty disables /scim/v2
+}
+
+// OIDCConfig configures single sign-on against an OpenID Connect provider
+type OIDCConfig struct {
+	Enabled       bool
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	RoleClaim     string   // ID token claim holding the user's roles or groups
+	AdminValues   []string // Role claim values that grant the admin role
+	AutoProvision bool     // Create local users on first login
+}
+
+// Load reads the configuration from the environment (and .env), the optional
+// YAML or TOML file named by CONFIG_FILE, and built-in defaults, then
+// validates it. All problems are reported together.
 func Load() (*Config, error) {
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Warning: .env file not found: %v", err)
 	}
 
-	// Parse database port
-	dbPort, err := strconv.Atoi(getEnv("DB_PORT", "5432"))
+	src, err := newSource(os.Getenv("CONFIG_FILE"))
 	if err != nil {
-		return nil, fmt.Errorf("invalid DB_PORT: %v", err)
+		return nil, err
 	}
 
-	// Parse JWT expiry
-	jwtExpiryStr := getEnv("JWT_EXPIRY", "24h")
-	jwtExpiry, err := time.ParseDuration(jwtExpiryStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid JWT_EXPIRY: %v", err)
+	env := src.get("ENV", "development")
+
+	// Development trusts local frontends, production trusts no origin until
+	// one is configured
+	defaultOrigins := ""
+	if env == "development" {
+		defaultOrigins = "http://localhost:3000,http://localhost:5173,http://127.0.0.1:3000"
+	}
+
+	// HSTS only makes sense behind HTTPS, which production always is
+	defaultHSTSMaxAge := "0s"
+	if env == "production" {
+		defaultHSTSMaxAge = "8760h"
 	}
 
+	// Payloads carry personal data, so production only sends them encrypted
+	defaultRequireHTTPS := strconv.FormatBool(env == "production")
+
 	config := &Config{
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     dbPort,
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "password"),
-			DBName:   getEnv("DB_NAME", "employee_db"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:             src.get("DB_HOST", "localhost"),
+			Port:             src.int("DB_PORT", "5432"),
+			User:             src.get("DB_USER", "postgres"),
+			Password:         src.get("DB_PASSWORD", defaultDBPassword),
+			DBName:           src.get("DB_NAME", "employee_db"),
+			SSLMode:          src.get("DB_SSLMODE", "disable"),
+			StatementTimeout: src.duration("DB_STATEMENT_TIMEOUT", "5s"),
+			MaxOpenConns:     src.int("DB_MAX_OPEN_CONNS", "25"),
+			MaxIdleConns:     src.int("DB_MAX_IDLE_CONNS", "5"),
+			ConnMaxLifetime:  src.duration("DB_CONN_MAX_LIFETIME", "0s"),
+			ConnMaxIdleTime:  src.duration("DB_CONN_MAX_IDLE_TIME", "0s"),
+			AutoMigrate:      src.bool("DB_AUTO_MIGRATE", "true"),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "default-secret-key"),
-			Expiry: jwtExpiry,
+			Secret:            src.get("JWT_SECRET", defaultJWTSecret),
+			Expiry:            src.duration("JWT_EXPIRY", "24h"),
+			RevocationRefresh: src.duration("JWT_REVOCATION_REFRESH", "30s"),
 		},
 		Server: ServerConfig{
-			Host: getEnv("SERVER_HOST", "localhost"),
-			Port: getEnv("SERVER_PORT", "8080"),
+			Host:              src.get("SERVER_HOST", "localhost"),
+			Port:              src.get("SERVER_PORT", "8080"),
+			ReadTimeout:       src.duration("SERVER_READ_TIMEOUT", "15s"),
+			ReadHeaderTimeout: src.duration("SERVER_READ_HEADER_TIMEOUT", "5s"),
+			WriteTimeout:      src.duration("SERVER_WRITE_TIMEOUT", "30s"),
+			IdleTimeout:       src.duration("SERVER_IDLE_TIMEOUT", "60s"),
+			ShutdownDelay:     src.duration("SHUTDOWN_DELAY", "5s"),
+			ShutdownTimeout:   src.duration("SHUTDOWN_TIMEOUT", "30s"),
+			ErrorFormat:       src.get("ERROR_FORMAT", "json"),
+			MaxBodySize:       src.size("SERVER_MAX_BODY_SIZE", "1MB"),
+			MaxImportBodySize: src.size("SERVER_MAX_IMPORT_BODY_SIZE", "32MB"),
+			MaxBatchSize:      src.int("SERVER_MAX_BATCH_SIZE", "1000"),
+			HSTSMaxAge:        src.duration("SERVER_HSTS_MAX_AGE", defaultHSTSMaxAge),
+			APIDocs:           src.bool("SERVER_API_DOCS", "true"),
+			TrustedProxies:    src.list("TRUSTED_PROXIES", ""),
+		},
+		OIDC: OIDCConfig{
+			Enabled:       src.bool("OIDC_ENABLED", "false"),
+			IssuerURL:     src.get("OIDC_ISSUER_URL", ""),
+			ClientID:      src.get("OIDC_CLIENT_ID", ""),
+			ClientSecret:  src.get("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:   src.get("OIDC_REDIRECT_URL", "http://localhost:8080/api/v1/auth/oidc/callback"),
+			Scopes:        src.list("OIDC_SCOPES", "openid,profile,email"),
+			RoleClaim:     src.get("OIDC_ROLE_CLAIM", "groups"),
+			AdminValues:   src.list("OIDC_ADMIN_VALUES", ""),
+			AutoProvision: src.bool("OIDC_AUTO_PROVISION", "true"),
+		},
+		Auth: AuthConfig{
+			Backends: src.list("AUTH_BACKENDS", "local"),
+		},
+		LDAP: LDAPConfig{
+			URL:                src.get("LDAP_URL", ""),
+			StartTLS:           src.bool("LDAP_START_TLS", "false"),
+			InsecureSkipVerify: src.bool("LDAP_INSECURE_SKIP_VERIFY", "false"),
+			BindDN:             src.get("LDAP_BIND_DN", ""),
+			BindPassword:       src.get("LDAP_BIND_PASSWORD", ""),
+			BaseDN:             src.get("LDAP_BASE_DN", ""),
+			UserFilter:         src.get("LDAP_USER_FILTER", "(uid=%s)"),
+			EmailAttribute:     src.get("LDAP_EMAIL_ATTRIBUTE", "mail"),
+			GroupAttribute:     src.get("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+			GroupRoles:         src.mapping("LDAP_GROUP_ROLES", ""),
+			Timeout:            src.duration("LDAP_TIMEOUT", "5s"),
+		},
+		SCIM: SCIMConfig{
+			Token: src.get("SCIM_TOKEN", ""),
+		},
+		Health: HealthConfig{
+			CheckTimeout:   src.duration("HEALTH_CHECK_TIMEOUT", "2s"),
+			PoolDegradedAt: src.float("HEALTH_POOL_DEGRADED_AT", "0.8"),
+		},
+		Metrics: MetricsConfig{
+			Address: src.get("METRICS_ADDRESS", ""),
 		},
-		Env: getEnv("ENV", "development"),
+		Tracing: TracingConfig{
+			Exporter:     src.get("TRACING_EXPORTER", "none"),
+			OTLPEndpoint: src.get("TRACING_OTLP_ENDPOINT", "localhost:4318"),
+			OTLPInsecure: src.bool("TRACING_OTLP_INSECURE", "false"),
+			SampleRatio:  src.float("TRACING_SAMPLE_RATIO", "1"),
+			ServiceName:  src.get("TRACING_SERVICE_NAME", "employee-api"),
+		},
+		Log: LogConfig{
+			Level: src.get("LOG_LEVEL", "info"),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:  src.bool("RATE_LIMIT_ENABLED", "true"),
+			Store:    src.get("RATE_LIMIT_STORE", "memory"),
+			Policies: src.rateLimits("RATE_LIMITS", "default=10/s:20,auth=10/m:5"),
+		},
+		Idempotency: IdempotencyConfig{
+			Enabled:     src.bool("IDEMPOTENCY_ENABLED", "true"),
+			TTL:         src.duration("IDEMPOTENCY_TTL", "24h"),
+			LockTimeout: src.duration("IDEMPOTENCY_LOCK_TIMEOUT", "1m"),
+		},
+		Webhook: WebhookConfig{
+			Enabled:         src.bool("WEBHOOK_ENABLED", "true"),
+			PollInterval:    src.duration("WEBHOOK_POLL_INTERVAL", "2s"),
+			Timeout:         src.duration("WEBHOOK_TIMEOUT", "10s"),
+			MaxAttempts:     src.int("WEBHOOK_MAX_ATTEMPTS", "8"),
+			RetryBackoff:    src.duration("WEBHOOK_RETRY_BACKOFF", "30s"),
+			RetryBackoffMax: src.duration("WEBHOOK_RETRY_BACKOFF_MAX", "6h"),
+			Concurrency:     src.int("WEBHOOK_CONCURRENCY", "4"),
+			Retention:       src.duration("WEBHOOK_RETENTION", "720h"),
+			RequireHTTPS:    src.bool("WEBHOOK_REQUIRE_HTTPS", defaultRequireHTTPS),
+		},
+		Events: EventsConfig{
+			Enabled:   src.bool("EVENTS_ENABLED", "true"),
+			Heartbeat: src.duration("EVENTS_HEARTBEAT", "15s"),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   src.list("CORS_ALLOWED_ORIGINS", defaultOrigins),
+			AllowCredentials: src.bool("CORS_ALLOW_CREDENTIALS", "true"),
+			ExposedHeaders:   src.list("CORS_EXPOSED_HEADERS", "ETag,Link,X-Request-ID,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After,Idempotent-Replayed"),
+			MaxAge:           src.duration("CORS_MAX_AGE", "10m"),
+		},
+		TLS: TLSConfig{
+			CertFile:       src.get("TLS_CERT_FILE", ""),
+			KeyFile:        src.get("TLS_KEY_FILE", ""),
+			ReloadInterval: src.duration("TLS_RELOAD_INTERVAL", "1m"),
+			MinVersion:     src.get("TLS_MIN_VERSION", "1.2"),
+			CipherSuites:   src.list("TLS_CIPHER_SUITES", ""),
+			ClientCAFile:   src.get("TLS_CLIENT_CA_FILE", ""),
+			ClientAuth:     src.get("TLS_CLIENT_AUTH", "none"),
+			ClientAccounts: src.mapping("TLS_CLIENT_ACCOUNTS", ""),
+		},
+		Bootstrap: BootstrapConfig{
+			AdminUsername: src.get("BOOTSTRAP_ADMIN_USERNAME", "admin"),
+			AdminEmail:    src.get("BOOTSTRAP_ADMIN_EMAIL", "admin@company.com"),
+			AdminPassword: src.get("BOOTSTRAP_ADMIN_PASSWORD", ""),
+		},
+		Env:      env,
+		settings: src.settings,
+	}
+
+	// Settings that failed to parse are zero; skip their validation errors
+	// so each problem is reported once
+	errs := append(src.errs, src.unknownKeys()...)
+	if err := config.Validate(); err != nil {
+		for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+			if !src.failed(err) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
 
 	return config, nil
 }
 
+// Validate checks the configuration for invalid combinations and, in
+// production, for insecure defaults. It reports every problem it finds.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Database.Port > 0 && c.Database.Port <= 65535, "invalid DB_PORT: must be between 1 and 65535")
+	check(c.Database.MaxOpenConns > 0, "invalid DB_MAX_OPEN_CONNS: must be at least 1")
+	check(c.Database.MaxIdleConns >= 0 && c.Database.MaxIdleConns <= c.Database.MaxOpenConns, "invalid DB_MAX_IDLE_CONNS: must be between 0 and DB_MAX_OPEN_CONNS")
+	check(c.JWT.Secret != "", "invalid JWT_SECRET: must not be empty")
+	check(c.JWT.Expiry > 0, "invalid JWT_EXPIRY: must be positive")
+	check(c.JWT.RevocationRefresh > 0, "invalid JWT_REVOCATION_REFRESH: must be positive")
+	check(c.Server.MaxBatchSize > 0, "invalid SERVER_MAX_BATCH_SIZE: must be at least 1")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, prefixErr := netip.ParsePrefix(proxy)
+		_, addrErr := netip.ParseAddr(proxy)
+		check(prefixErr == nil || addrErr == nil, "invalid TRUSTED_PROXIES: %q is not an IP address or CIDR", proxy)
+	}
+	check(c.Server.ErrorFormat == "json" || c.Server.ErrorFormat == "problem", "invalid ERROR_FORMAT: must be json or problem")
+	check(slices.Contains([]string{"debug", "info", "warn", "error"}, strings.ToLower(c.Log.Level)), "invalid LOG_LEVEL: must be debug, info, warn or error")
+	check(slices.Contains([]string{"none", "otlp", "stdout"}, c.Tracing.Exporter), "invalid TRACING_EXPORTER: must be none, otlp or stdout")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "invalid TRACING_SAMPLE_RATIO: must be between 0 and 1")
+	check(c.Health.PoolDegradedAt > 0 && c.Health.PoolDegradedAt <= 1, "invalid HEALTH_POOL_DEGRADED_AT: must be between 0 and 1")
+	check(c.RateLimit.Store == "memory" || c.RateLimit.Store == "postgres", "invalid RATE_LIMIT_STORE: must be memory or postgres")
+	check(c.Idempotency.TTL > 0, "invalid IDEMPOTENCY_TTL: must be positive")
+	check(c.Idempotency.LockTimeout > c.Server.WriteTimeout, "invalid IDEMPOTENCY_LOCK_TIMEOUT: must be longer than SERVER_WRITE_TIMEOUT")
+	check(c.Webhook.PollInterval > 0, "invalid WEBHOOK_POLL_INTERVAL: must be positive")
+	check(c.Webhook.Timeout > 0, "invalid WEBHOOK_TIMEOUT: must be positive")
+	check(c.Webhook.MaxAttempts > 0 && c.Webhook.MaxAttempts <= 100, "invalid WEBHOOK_MAX_ATTEMPTS: must be between 1 and 100")
+	check(c.Webhook.RetryBackoff > 0 && c.Webhook.RetryBackoff <= c.Webhook.RetryBackoffMax, "invalid WEBHOOK_RETRY_BACKOFF: must be positive and at most WEBHOOK_RETRY_BACKOFF_MAX")
+	check(c.Webhook.Concurrency > 0, "invalid WEBHOOK_CONCURRENCY: must be at least 1")
+	check(c.Webhook.Retention > 0, "invalid WEBHOOK_RETENTION: must be positive")
+	check(c.Events.Heartbeat > 0, "invalid EVENTS_HEARTBEAT: must be positive")
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"), "invalid CORS_ALLOWED_ORIGINS: * cannot be combined with CORS_ALLOW_CREDENTIALS=true")
+
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "invalid TLS_KEY_FILE: TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(c.TLS.MinVersion == "1.2" || c.TLS.MinVersion == "1.3", "invalid TLS_MIN_VERSION: must be 1.2 or 1.3")
+	check(c.TLS.ReloadInterval > 0, "invalid TLS_RELOAD_INTERVAL: must be positive")
+	check(slices.Contains([]string{"none", "optional", "require"}, c.TLS.ClientAuth), "invalid TLS_CLIENT_AUTH: must be none, optional or require")
+	for _, name := range c.TLS.CipherSuites {
+		check(slices.ContainsFunc(tls.CipherSuites(), func(suite *tls.CipherSuite) bool { return suite.Name == name }),
+			"invalid TLS_CIPHER_SUITES: unknown or insecure cipher suite %q", name)
+	}
+	if c.TLS.ClientAuth != "none" || len(c.TLS.ClientAccounts) > 0 {
+		check(c.TLS.CertFile != "", "invalid TLS_CLIENT_AUTH: client certificates require TLS_CERT_FILE")
+		check(c.TLS.ClientCAFile != "", "invalid TLS_CLIENT_CA_FILE: required to verify client certificates")
+	}
+
+	for _, backend := range c.Auth.Backends {
+		check(backend == "local" || backend == "ldap", "invalid AUTH_BACKENDS: unknown backend %q", backend)
+	}
+	if slices.Contains(c.Auth.Backends, "ldap") {
+		check(c.LDAP.URL != "", "invalid LDAP_URL: required when AUTH_BACKENDS includes ldap")
+		check(c.LDAP.BaseDN != "", "invalid LDAP_BASE_DN: required when AUTH_BACKENDS includes ldap")
+		check(strings.Count(c.LDAP.UserFilter, "%s") == 1, "invalid LDAP_USER_FILTER: must contain exactly one %%s")
+	}
+	if c.OIDC.Enabled {
+		check(c.OIDC.IssuerURL != "", "invalid OIDC_ISSUER_URL: required when OIDC_ENABLED=true")
+		check(c.OIDC.ClientID != "", "invalid OIDC_CLIENT_ID: required when OIDC_ENABLED=true")
+		check(c.OIDC.RedirectURL != "", "invalid OIDC_REDIRECT_URL: required when OIDC_ENABLED=true")
+	}
+
+	// Refuse to run production on values that are only safe on a laptop
+	if c.Env == "production" {
+		check(c.JWT.Secret != defaultJWTSecret && len(c.JWT.Secret) >= 32, "invalid JWT_SECRET: production requires a random secret of at least 32 characters")
+		check(c.Database.Password != defaultDBPassword, "invalid DB_PASSWORD: production must not use the default password")
+		check(c.Database.SSLMode != "disable", "invalid DB_SSLMODE: production must not disable TLS to the database")
+		check(!c.LDAP.InsecureSkipVerify, "invalid LDAP_INSECURE_SKIP_VERIFY: must be false in production")
+		check(!slices.Contains(c.CORS.AllowedOrigins, "*"), "invalid CORS_ALLOWED_ORIGINS: * is not allowed in production")
+	}
+
+	return errors.Join(errs...)
+}
+
 func (c *Config) GetDatabaseDSN() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		c.Database.Host,
+	// lib/pq passes unknown keys such as statement_timeout to the server as
+	// session settings
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s statement_timeout=%d",
+		quoteDSN(c.Database.Host),
 		c.Database.Port,
-		c.Database.User,
-		c.Database.Password,
-		c.Database.DBName,
-		c.Database.SSLMode,
+		quoteDSN(c.Database.User),
+		quoteDSN(c.Database.Password),
+		quoteDSN(c.Database.DBName),
+		quoteDSN(c.Database.SSLMode),
+		c.Database.StatementTimeout.Milliseconds(),
 	)
 }
 
+// quoteDSN quotes a connection string value so spaces, quotes and
+// backslashes (common in generated passwords) cannot break it up
+func quoteDSN(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
 func (c *Config) GetServerAddress() string {
 	return fmt.Sprintf("%s:%s", c.Server.Host, c.Server.Port)
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// splitList parses a comma-separated value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	return defaultValue
-}
\ No newline at end of file
+	return items
+}
+
+// parseMapping parses "key:value,key:value" pairs
+func parseMapping(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, item := range splitList(value) {
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("expected key:value, got %q", item)
+		}
+		mapping[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return mapping, nil
+}
+
+// parseRateLimits parses "group=requests/unit:burst" policies, e.g.
+// "default=10/s:20,auth=10/m:5". The burst defaults to the request count.
+func parseRateLimits(value string) (map[string]RateLimitPolicy, error) {
+	units := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+
+	policies := make(map[string]RateLimitPolicy)
+	for _, item := range splitList(value) {
+		group, spec, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(group) == "" {
+			return nil, fmt.Errorf("expected group=requests/unit[:burst], got %q", item)
+		}
+
+		spec, burstStr, hasBurst := strings.Cut(strings.TrimSpace(spec), ":")
+		countStr, unit, ok := strings.Cut(spec, "/")
+		count, err := strconv.Atoi(countStr)
+		if !ok || err != nil || count <= 0 || units[unit] == 0 {
+			return nil, fmt.Errorf("expected group=requests/unit[:burst] with unit s, m or h, got %q", item)
+		}
+
+		burst := count
+		if hasBurst {
+			if burst, err = strconv.Atoi(burstStr); err != nil || burst <= 0 {
+				return nil, fmt.Errorf("invalid burst in %q", item)
+			}
+		}
+
+		policies[strings.TrimSpace(group)] = RateLimitPolicy{
+			Rate:  float64(count) / units[unit].Seconds(),
+			Burst: burst,
+		}
+	}
+	return policies, nil
+}
+
+// parseSize parses a byte size such as "512KB", "1MB" or "1048576"; units
+// are powers of 1024
+func parseSize(value string) (int64, error) {
+	units := []struct {
+		suffix string
+		factor int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+	value = strings.ToUpper(strings.TrimSpace(value))
+	factor := int64(1)
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			value, factor = strings.TrimSpace(number), unit.factor
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("expected a positive size such as 1MB, got %q", value)
+	}
+	return size * factor, nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestGetDatabaseDSN(t *testing.T) {
+	c := &Config{Database: DatabaseConfig{
+		Host:             "db.internal",
+		Port:             5432,
+		User:             "app user",
+		Password:         `p@ss w'rd\ sslmode=disable`,
+		DBName:           "employee_db",
+		SSLMode:          "verify-full",
+		StatementTimeout: 5 * time.Second,
+	}}
+
+	dsn := c.GetDatabaseDSN()
+	want := `host='db.internal' port=5432 user='app user' password='p@ss w\'rd\\ sslmode=disable' ` +
+		`dbname='employee_db' sslmode='verify-full' statement_timeout=5000`
+	if dsn != want {
+		t.Errorf("GetDatabaseDSN() =\n%s\nwant\n%s", dsn, want)
+	}
+	if _, err := pq.NewConnector(dsn); err != nil {
+		t.Errorf("lib/pq rejects the DSN: %v", err)
+	}
+}
+
+func TestLoadExampleConfig(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "../config.example.yaml")
+
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "db:\n  hots: localhost\n"))
+	t.Setenv("DB_PORT", "five")
+	t.Setenv("JWT_EXPIRY", "-1h")
+	t.Setenv("ERROR_FORMAT", "xml")
+	t.Setenv("WEBHOOK_TIMEOUT", "soon")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load succeeded, want errors")
+	}
+
+	for _, want := range []string{
+		"invalid DB_PORT:",
+		"invalid JWT_EXPIRY:",
+		"invalid ERROR_FORMAT:",
+		"invalid WEBHOOK_TIMEOUT:",
+		"unknown setting DB_HOTS in config file",
+	} {
+		// Settings that failed to parse are not reported again by Validate
+		if n := strings.Count(err.Error(), want); n != 1 {
+			t.Errorf("%q reported %d times in:\n%v", want, n, err)
+		}
+	}
+}
+
+func TestValidateProduction(t *testing.T) {
+	t.Setenv("ENV", "production")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load succeeded with development defaults in production")
+	}
+	for _, want := range []string{"invalid JWT_SECRET:", "invalid DB_PASSWORD:", "invalid DB_SSLMODE:", "invalid CORS_ALLOWED_ORIGINS:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10,::1,proxy.internal")
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), `invalid TRUSTED_PROXIES: "proxy.internal"`) {
+		t.Fatalf("Load = %v, want an error for proxy.internal only", err)
+	}
+	if strings.Count(err.Error(), "TRUSTED_PROXIES") != 1 {
+		t.Errorf("valid proxies were rejected: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Setting is one resolved configuration value and where it came from
+type Setting struct {
+	Key    string
+	Value  string
+	Source string // "env", "secret file", "config file" or "default"
+}
+
+// source resolves settings by key. Precedence, highest first: the KEY
+// environment variable, a file named by KEY_FILE (Docker secrets), the config
+// file, the built-in default. Parse errors are collected so Load can report
+// all of them at once.
+type source struct {
+	file     map[string]string
+	used     map[string]bool
+	settings []Setting
+	errs     []error
+}
+
+// newSource reads the YAML or TOML config file at path, if any. Nested keys
+// are joined with "_" and upper-cased, so db.host sets DB_HOST.
+func newSource(path string) (*source, error) {
+	s := &source{file: make(map[string]string), used: make(map[string]bool)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var tree map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("unsupported config file type %q: use .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	flatten("", tree, s.file)
+	return s, nil
+}
+
+// flatten turns nested maps into KEY_NAME entries; lists become
+// comma-separated values
+func flatten(prefix string, value any, out map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			name := strings.ToUpper(key)
+			if prefix != "" {
+				name = prefix + "_" + name
+			}
+			flatten(name, child, out)
+		}
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		out[prefix] = strings.Join(items, ",")
+	case nil:
+	default:
+		out[prefix] = fmt.Sprint(v)
+	}
+}
+
+// get returns the raw value of key
+func (s *source) get(key, defaultValue string) string {
+	value, origin := defaultValue, "default"
+	if env := os.Getenv(key); env != "" {
+		value, origin = env, "env"
+	} else if path := os.Getenv(key + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			s.errs = append(s.errs, fmt.Errorf("invalid %s_FILE: %v", key, err))
+		}
+		value, origin = strings.TrimRight(string(data), "\r\n"), "secret file"
+	} else if fileValue, ok := s.file[key]; ok {
+		value, origin = fileValue, "config file"
+	}
+
+	s.used[key] = true
+	s.settings = append(s.settings, Setting{Key: key, Value: value, Source: origin})
+	return value
+}
+
+func (s *source) invalid(key string, err error) {
+	s.errs = append(s.errs, fmt.Errorf("invalid %s: %v", key, err))
+}
+
+// failed reports whether err is about a setting that already failed to parse
+func (s *source) failed(err error) bool {
+	for _, parseErr := range s.errs {
+		key, _, _ := strings.Cut(parseErr.Error(), ":")
+		if strings.HasPrefix(err.Error(), key+":") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *source) int(key, defaultValue string) int {
+	value, err := strconv.Atoi(s.get(key, defaultValue))
+	if err != nil {
+		s.invalid(key, err)
+	}
+	return value
+}
+
+func (s *source) bool(key, defaultValue string) bool {
+	value, err := strconv.ParseBool(s.get(key, defaultValue))
+	if err != nil {
+		s.invalid(key, err)
+	}
+	return value
+}
+
+func (s *source) float(key, defaultValue string) float64 {
+	value, err := strconv.ParseFloat(s.get(key, defaultValue), 64)
+	if err != nil {
+		s.invalid(key, err)
+	}
+	return value
+}
+
+func (s *source) duration(key, defaultValue string) time.Duration {
+	value, err := time.ParseDuration(s.get(key, defaultValue))
+	if err != nil {
+		s.invalid(key, err)
+	}
+	return value
+}
+
+func (s *source) size(key, defaultValue string) int64 {
+	value, err := parseSize(s.get(key, defaultValue))
+	if err != nil {
+		s.invalid(key, err)
+	}
+	return value
+}
+
+func (s *source) list(key, defaultValue string) []string {
+	return splitList(s.get(key, defaultValue))
+}
+
+func (s *source) mapping(key, defaultValue string) map[string]string {
+	value, err := parseMapping(s.get(key, defaultValue))
+	if err != nil {
+		s.invalid(key, err)
+	}
+	return value
+}
+
+func (s *source) rateLimits(key, defaultValue string) map[string]RateLimitPolicy {
+	value, err := parseRateLimits(s.get(key, defaultValue))
+	if err != nil {
+		s.invalid(key, err)
+	}
+	return value
+}
+
+// unknownKeys reports config file entries no setting reads, which are
+// almost always typos
+func (s *source) unknownKeys() []error {
+	var keys []string
+	for key := range s.file {
+		if !s.used[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		errs[i] = fmt.Errorf("unknown setting %s in config file", key)
+	}
+	return errs
+}
+
+// Settings lists every setting with its effective value, sorted by key.
+// With redacted set, passwords, secrets and tokens are masked.
+func (c *Config) Settings(redacted bool) []Setting {
+	settings := make([]Setting, len(c.settings))
+	copy(settings, c.settings)
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+
+	if redacted {
+		for i, setting := range settings {
+			if setting.Value != "" && isSecret(setting.Key) {
+				settings[i].Value = "[REDACTED]"
+			}
+		}
+	}
+	return settings
+}
+
+func isSecret(key string) bool {
+	for _, marker := range []string{"PASSWORD", "SECRET", "TOKEN"} {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
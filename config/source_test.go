@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewSourceFlattens(t *testing.T) {
+	want := map[string]string{
+		"DB_HOST":                    "db.internal",
+		"DB_PORT":                    "5433",
+		"DB_AUTO_MIGRATE":            "false",
+		"BOOTSTRAP_ADMIN_USERNAME":   "root",
+		"CORS_ALLOWED_ORIGINS":       "https://a.example.com,https://b.example.com",
+		"RATE_LIMIT_REQUESTS_PER_IP": "10",
+	}
+
+	files := map[string]string{
+		"config.yaml": `
+db:
+  host: db.internal
+  port: 5433
+  auto_migrate: false
+bootstrap:
+  admin:
+    username: root
+cors:
+  allowed_origins: [https://a.example.com, https://b.example.com]
+rate_limit:
+  requests_per_ip: 10
+tracing:
+  endpoint:
+`,
+		"config.toml": `
+[db]
+host = "db.internal"
+port = 5433
+auto_migrate = false
+
+[bootstrap.admin]
+username = "root"
+
+[cors]
+allowed_origins = ["https://a.example.com", "https://b.example.com"]
+
+[rate_limit]
+requests_per_ip = 10
+`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			src, err := newSource(writeFile(t, name, content))
+			if err != nil {
+				t.Fatalf("newSource: %v", err)
+			}
+			if !reflect.DeepEqual(src.file, want) {
+				t.Errorf("flattened = %v, want %v", src.file, want)
+			}
+		})
+	}
+}
+
+func TestNewSourceRejects(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.json": `{"db": {"host": "localhost"}}`,
+		"config.yaml": "db: [unterminated",
+		"config.toml": "[db\nhost = 1",
+	} {
+		if _, err := newSource(writeFile(t, name, content)); err == nil {
+			t.Errorf("newSource(%s) succeeded, want an error", name)
+		}
+	}
+	if _, err := newSource(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("newSource of a missing file succeeded, want an error")
+	}
+}
+
+func TestSourcePrecedence(t *testing.T) {
+	src, err := newSource(writeFile(t, "config.yaml", "db:\n  password: from-file\n  user: file-user\n  name: file-db\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := writeFile(t, "db_password", "from-secret\n")
+
+	// The environment beats a secret file, which beats the config file,
+	// which beats the default
+	t.Setenv("DB_USER", "env-user")
+	t.Setenv("DB_USER_FILE", secret)
+	t.Setenv("DB_PASSWORD_FILE", secret)
+
+	tests := []struct {
+		key    string
+		value  string
+		origin string
+	}{
+		{"DB_USER", "env-user", "env"},
+		{"DB_PASSWORD", "from-secret", "secret file"},
+		{"DB_NAME", "file-db", "config file"},
+		{"DB_HOST", "localhost", "default"},
+	}
+	for _, tt := range tests {
+		if got := src.get(tt.key, "localhost"); got != tt.value {
+			t.Errorf("get(%s) = %q, want %q", tt.key, got, tt.value)
+		}
+		setting := src.settings[len(src.settings)-1]
+		if setting.Source != tt.origin {
+			t.Errorf("%s source = %q, want %q", tt.key, setting.Source, tt.origin)
+		}
+	}
+
+	t.Setenv("JWT_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+	src.get("JWT_SECRET", "default")
+	if len(src.errs) != 1 {
+		t.Errorf("errs = %v, want one error for the unreadable secret file", src.errs)
+	}
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// CopyRows bulk-loads rows into table with COPY in one transaction. Each row
+// holds a value per column, in the order of columns.
+func (db *DB) CopyRows(ctx context.Context, table string, columns []string, rows [][]any) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return fmt.Errorf("failed to start COPY into %s: %v", table, err)
+	}
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			return fmt.Errorf("failed to copy row into %s: %v", table, err)
+		}
+	}
+	// The final Exec without arguments flushes the buffered rows
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return fmt.Errorf("failed to copy into %s: %v", table, err)
+	}
+	if err := stmt.Close(); err != nil {
+		return fmt.Errorf("failed to finish COPY into %s: %v", table, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit COPY into %s: %v", table, err)
+	}
+	return nil
+}
@@ -1,21 +1,30 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"go-crud-employee/config"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 type DB struct {
 	*sql.DB
 }
 
+// NewConnection opens the connection pool. Queries are traced through the
+// global tracer provider, so tracing must be set up first.
 func NewConnection(cfg *config.Config) (*DB, error) {
-	db, err := sql.Open("postgres", cfg.GetDatabaseDSN())
+	db, err := otelsql.Open("postgres", cfg.GetDatabaseDSN(),
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitConnectorConnect: true}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %v", err)
 	}
@@ -26,10 +35,12 @@ func NewConnection(cfg *config.Config) (*DB, error) {
 	}
 
 	// Set connection pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
 
-	log.Println("Database connection established successfully")
+	slog.Info("Database connection established")
 
 	return &DB{db}, nil
 }
@@ -38,86 +49,42 @@ func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
-// CreateTables creates the necessary tables for the application
-func (db *DB) CreateTables() error {
-	// Create users table for authentication
-	userTableQuery := `
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		username VARCHAR(50) UNIQUE NOT NULL,
-		email VARCHAR(100) UNIQUE NOT NULL,
-		password_hash VARCHAR(255) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	// Create employees table
-	employeeTableQuery := `
-	CREATE TABLE IF NOT EXISTS employees (
-		id SERIAL PRIMARY KEY,
-		nip VARCHAR(20) UNIQUE NOT NULL,
-		name VARCHAR(100) NOT NULL,
-		email VARCHAR(100) UNIQUE NOT NULL,
-		phone VARCHAR(20),
-		position VARCHAR(100) NOT NULL,
-		department VARCHAR(100) NOT NULL,
-		salary DECIMAL(15,2),
-		hire_date DATE NOT NULL,
-		is_active BOOLEAN DEFAULT true,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	// Create indexes for better performance
-	indexQueries := []string{
-		"CREATE INDEX IF NOT EXISTS idx_employees_nip ON employees(nip);",
-		"CREATE INDEX IF NOT EXISTS idx_employees_email ON employees(email);",
-		"CREATE INDEX IF NOT EXISTS idx_employees_department ON employees(department);",
-		"CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);",
-		"CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);",
+// BootstrapAdmin creates the first admin account from a password hash. It
+// does nothing once any admin exists, so it is safe to call on every start.
+func (db *DB) BootstrapAdmin(ctx context.Context, username, email, passwordHash string) (bool, error) {
+	result, err := db.ExecContext(ctx, `INSERT INTO users (username, email, password_hash, role)
+		SELECT $1, $2, $3, 'admin'
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin')
+		ON CONFLICT DO NOTHING`, username, email, passwordHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to create admin user: %v", err)
 	}
 
-	// Execute table creation queries
-	queries := []string{userTableQuery, employeeTableQuery}
-	queries = append(queries, indexQueries...)
-
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute query: %v", err)
-		}
+	created, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to create admin user: %v", err)
 	}
-
-	log.Println("Database tables created successfully")
-	return nil
+	return created > 0, nil
 }
 
-// CreateDefaultUser creates a default admin user for testing
-func (db *DB) CreateDefaultUser() error {
-	// Check if default user already exists
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE username = $1", "admin").Scan(&count)
+// LoadRevokedSessions returns revoked sessions that have not yet expired, keyed by session ID
+func (db *DB) LoadRevokedSessions() (map[string]time.Time, error) {
+	rows, err := db.Query(`SELECT id, expires_at FROM user_sessions
+		WHERE revoked_at IS NOT NULL AND expires_at > now()`)
 	if err != nil {
-		return fmt.Errorf("failed to check existing user: %v", err)
+		return nil, fmt.Errorf("failed to load revoked sessions: %v", err)
 	}
-
-	if count > 0 {
-		log.Println("Default admin user already exists")
-		return nil
-	}
-
-	// Create default user with hashed password
-	// Password: "admin123" (you should change this in production)
-	hashedPassword := "$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi" // bcrypt hash of "admin123"
-
-	query := `
-	INSERT INTO users (username, email, password_hash) 
-	VALUES ($1, $2, $3)`
-
-	_, err = db.Exec(query, "admin", "admin@company.com", hashedPassword)
-	if err != nil {
-		return fmt.Errorf("failed to create default user: %v", err)
+	defer rows.Close()
+
+	revoked := make(map[string]time.Time)
+	for rows.Next() {
+		var id string
+		var expiresAt time.Time
+		if err := rows.Scan(&id, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revoked session: %v", err)
+		}
+		revoked[id] = expiresAt
 	}
 
-	log.Println("Default admin user created successfully (username: admin, password: admin123)")
-	return nil
-}
\ No newline at end of file
+	return revoked, rows.Err()
+}
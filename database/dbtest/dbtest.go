@@ -0,0 +1,49 @@
+// Package dbtest connects tests to the Postgres database named by
+// TEST_DATABASE_URL. Tests that need it are skipped when it is not set, so
+// `go test ./...` passes without a database.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"go-crud-employee/database"
+)
+
+// Open connects to the test database and migrates it to the latest schema
+func Open(t testing.TB) *database.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := conn.Ping(); err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	db := &database.DB{DB: conn}
+	if _, err := db.MigrateUp(context.Background()); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+// Truncate empties tables and resets their sequences
+func Truncate(t testing.TB, db *database.DB, tables ...string) {
+	t.Helper()
+
+	query := "TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE"
+	if _, err := db.ExecContext(context.Background(), query); err != nil {
+		t.Fatalf("failed to truncate %v: %v", tables, err)
+	}
+}
@@ -0,0 +1,90 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"go-crud-employee/database"
+	"go-crud-employee/database/dbtest"
+
+	"github.com/lib/pq"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := database.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d has version %d, want versions without gaps", i, migration.Version)
+		}
+	}
+}
+
+func TestMigrateWaitsPastStatementTimeout(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	// One connection, so the session's statement_timeout applies to the
+	// migration, as the one in the DSN does
+	conn, err := sql.Open("postgres", os.Getenv("TEST_DATABASE_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetMaxOpenConns(1)
+	if _, err := conn.ExecContext(ctx, "SET statement_timeout = 100"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another replica migrating holds the lock for longer than the timeout
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(7263541)"); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(300*time.Millisecond, func() { tx.Rollback() })
+
+	if _, err := (&database.DB{DB: conn}).MigrateUp(ctx); err != nil {
+		t.Fatalf("MigrateUp while another replica migrates: %v", err)
+	}
+
+	// The timeout still applies outside the migration
+	if _, err := conn.ExecContext(ctx, "SELECT pg_sleep(0.3)"); err == nil {
+		t.Error("statement_timeout no longer applies after migrating")
+	}
+}
+
+func TestTimesAreAbsolute(t *testing.T) {
+	db := dbtest.Open(t)
+
+	// Tables whose times are compared against now() or Go's clock
+	tables := []string{
+		"user_sessions", "oidc_login_states", "rate_limit_buckets", "idempotency_keys",
+		"webhook_subscriptions", "outbox_events", "webhook_deliveries", "webhook_delivery_attempts",
+	}
+	rows, err := db.QueryContext(context.Background(), `
+		SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ANY($1)
+			AND data_type = 'timestamp without time zone'`, pq.Array(tables))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			t.Fatal(err)
+		}
+		t.Errorf("%s.%s is TIMESTAMP, want TIMESTAMPTZ", table, column)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,15 @@
+-- Responses of POST requests sent with an Idempotency-Key header. A row with
+-- no status_code is a request still being processed.
+CREATE TABLE idempotency_keys (
+	scope VARCHAR(100) NOT NULL,
+	key VARCHAR(255) NOT NULL,
+	fingerprint CHAR(64) NOT NULL,
+	status_code INTEGER,
+	content_type VARCHAR(255),
+	response_body BYTEA,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
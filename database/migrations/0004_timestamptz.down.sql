@@ -0,0 +1,15 @@
+ALTER TABLE idempotency_keys
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN expires_at TYPE TIMESTAMP;
+
+ALTER TABLE rate_limit_buckets
+	ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE oidc_login_states
+	ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE user_sessions
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN last_seen_at TYPE TIMESTAMP,
+	ALTER COLUMN expires_at TYPE TIMESTAMP,
+	ALTER COLUMN revoked_at TYPE TIMESTAMP;
@@ -0,0 +1,19 @@
+-- Columns compared against Go's clock or the database's now() store an
+-- absolute instant, so the result no longer depends on the time zone of
+-- the API host or the database session. Existing values are read in the
+-- session time zone, which is how they were compared before.
+ALTER TABLE user_sessions
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN last_seen_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN revoked_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE oidc_login_states
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE rate_limit_buckets
+	ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE idempotency_keys
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN expires_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+-- Hash of the cookie set on the browser that started the login; the
+-- callback is only accepted from that browser
+ALTER TABLE oidc_login_states ADD COLUMN binding_hash CHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- Transaction that logged each event. Readers follow the log in
+-- transaction order and stop at the oldest running transaction, so they
+-- never pass an event that has yet to commit. Existing events all get the
+-- ID of this migration's transaction, which keeps them in ID order.
+ALTER TABLE outbox_events ADD COLUMN tx_id BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint;
+
+CREATE INDEX idx_outbox_events_position ON outbox_events(tx_id, id);
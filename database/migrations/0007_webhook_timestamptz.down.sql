@@ -0,0 +1,16 @@
+ALTER TABLE webhook_delivery_attempts
+	ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE webhook_deliveries
+	ALTER COLUMN next_attempt_at TYPE TIMESTAMP,
+	ALTER COLUMN delivered_at TYPE TIMESTAMP,
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE outbox_events
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN dispatched_at TYPE TIMESTAMP;
+
+ALTER TABLE webhook_subscriptions
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN updated_at TYPE TIMESTAMP;
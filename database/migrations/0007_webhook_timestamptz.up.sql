@@ -0,0 +1,20 @@
+-- The webhook tables were created after 0004 and kept TIMESTAMP, so due
+-- deliveries, retry backoff and outbox pruning still depended on the
+-- session time zone. Existing values are read in the session time zone, as
+-- in 0004.
+ALTER TABLE webhook_subscriptions
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE outbox_events
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN dispatched_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE webhook_deliveries
+	ALTER COLUMN next_attempt_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN delivered_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE webhook_delivery_attempts
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// queryCanceled is the SQLSTATE Postgres reports for cancelled statements,
+// whether by statement_timeout or on behalf of a cancelled context
+const queryCanceled = "57014"
+
+// IsTimeout reports whether err comes from the statement timeout or an
+// expired context deadline
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == queryCanceled && strings.Contains(pqErr.Message, "statement timeout")
+}
+
+// IsCanceled reports whether err was caused by ctx being cancelled, which for
+// request contexts means the client went away
+func IsCanceled(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled)
+}
+
+// QueryContext runs a query and logs it by name when it times out
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	logQueryError(ctx, query, err)
+	return rows, err
+}
+
+// QueryRowContext runs a single-row query and logs it by name when it times out
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	logQueryError(ctx, query, row.Err())
+	return row
+}
+
+// ExecContext runs a statement and logs it by name when it times out
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	logQueryError(ctx, query, err)
+	return result, err
+}
+
+// Tx is a transaction whose statements are logged like those of DB
+type Tx struct {
+	*sql.Tx
+}
+
+// BeginTx starts a transaction that logs its statements by name when they
+// time out
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx}, nil
+}
+
+// QueryContext runs a query and logs it by name when it times out
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	logQueryError(ctx, query, err)
+	return rows, err
+}
+
+// QueryRowContext runs a single-row query and logs it by name when it times out
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	logQueryError(ctx, query, row.Err())
+	return row
+}
+
+// ExecContext runs a statement and logs it by name when it times out
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	logQueryError(ctx, query, err)
+	return result, err
+}
+
+func logQueryError(ctx context.Context, query string, err error) {
+	switch {
+	case err == nil:
+	case IsTimeout(err):
+		slog.WarnContext(ctx, "Query timed out", "query", QueryName(query), "error", err)
+	case IsCanceled(ctx, err):
+		slog.DebugContext(ctx, "Query canceled", "query", QueryName(query))
+	}
+}
+
+var (
+	queryTablePattern  = regexp.MustCompile(`(?is)^\s*(select|insert|update|delete)\b.*?\b(?:from|into|update)\s+([a-z_][a-z0-9_]*)`)
+	updateTablePattern = regexp.MustCompile(`(?is)^\s*update\s+([a-z_][a-z0-9_]*)`)
+)
+
+// QueryName derives a short, stable name such as "employees.count" or
+// "users.update" from a statement, for logs that must not carry full SQL
+func QueryName(query string) string {
+	if match := updateTablePattern.FindStringSubmatch(query); match != nil {
+		return strings.ToLower(match[1]) + ".update"
+	}
+
+	match := queryTablePattern.FindStringSubmatch(query)
+	if match == nil {
+		return "query"
+	}
+
+	verb := strings.ToLower(match[1])
+	if verb == "select" && strings.Contains(strings.ToUpper(query), "COUNT(") {
+		verb = "count"
+	}
+	return strings.ToLower(match[2]) + "." + verb
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// timeoutDriver is a database whose every statement hits the statement timeout
+type timeoutDriver struct{}
+
+type timeoutConn struct{}
+
+type timeoutStmt struct{}
+
+var errStatementTimeout = &pq.Error{Code: queryCanceled, Message: "canceling statement due to statement timeout"}
+
+func (timeoutDriver) Open(string) (driver.Conn, error) { return timeoutConn{}, nil }
+
+func (timeoutConn) Prepare(string) (driver.Stmt, error) { return timeoutStmt{}, nil }
+func (timeoutConn) Close() error                        { return nil }
+func (timeoutConn) Begin() (driver.Tx, error)           { return timeoutConn{}, nil }
+func (timeoutConn) Commit() error                       { return nil }
+func (timeoutConn) Rollback() error                     { return nil }
+
+func (timeoutStmt) Close() error                               { return nil }
+func (timeoutStmt) NumInput() int                              { return -1 }
+func (timeoutStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errStatementTimeout }
+func (timeoutStmt) Query([]driver.Value) (driver.Rows, error)  { return nil, errStatementTimeout }
+
+func init() {
+	sql.Register("timeout", timeoutDriver{})
+}
+
+// captureLogs sends the default logger to a buffer for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestTimeoutsAreLogged(t *testing.T) {
+	conn, err := sql.Open("timeout", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	db := &DB{DB: conn}
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{"db query", func() error { _, err := db.QueryContext(ctx, "SELECT id FROM employees"); return err }, "employees.select"},
+		{"db query row", func() error { return db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Err() }, "users.count"},
+		{"db exec", func() error { _, err := db.ExecContext(ctx, "DELETE FROM user_sessions"); return err }, "user_sessions.delete"},
+		{"tx query", func() error { _, err := tx.QueryContext(ctx, "SELECT id FROM employees"); return err }, "employees.select"},
+		{"tx query row", func() error { return tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Err() }, "users.count"},
+		{"tx exec", func() error { _, err := tx.ExecContext(ctx, "UPDATE employees SET name = $1", "x"); return err }, "employees.update"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			if err := tt.run(); !IsTimeout(err) {
+				t.Fatalf("error = %v, want a statement timeout", err)
+			}
+			if !strings.Contains(logs.String(), `msg="Query timed out" query=`+tt.want) {
+				t.Errorf("log = %q, want a timeout logged as %s", logs, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT id, name FROM employees WHERE id = $1", "employees.select"},
+		{"select count(*) from employees where is_active", "employees.count"},
+		{"\n\t\tINSERT INTO users (username) VALUES ($1)", "users.insert"},
+		{"UPDATE webhook_deliveries SET status = $1", "webhook_deliveries.update"},
+		{"DELETE FROM outbox_events WHERE id = $1", "outbox_events.delete"},
+		{"WITH due AS (SELECT 1) UPDATE x SET y = 1", "query"},
+		{"SELECT pg_advisory_xact_lock($1)", "query"},
+	}
+	for _, tt := range tests {
+		if got := QueryName(tt.query); got != tt.want {
+			t.Errorf("QueryName(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
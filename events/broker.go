@@ -0,0 +1,267 @@
+// Package events streams employee changes to live subscribers. It reads the
+// webhook outbox, which doubles as a persisted event log, and wakes up on
+// Postgres notifications so every replica sees every change.
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-crud-employee/database"
+	"go-crud-employee/webhook"
+
+	"github.com/lib/pq"
+)
+
+// pageSize bounds how many events one query reads from the log
+const pageSize = 500
+
+// subscriberBuffer is how far a subscriber may fall behind before it is
+// dropped; it then resumes from the log with Last-Event-ID
+const subscriberBuffer = 256
+
+// heldRetry is how soon the log is read again while committed events wait
+// for an older transaction to end
+const heldRetry = 100 * time.Millisecond
+
+// Event is an entry of the event log
+type Event struct {
+	ID        int64
+	TxID      int64 // Transaction that logged the event
+	Type      string
+	Data      json.RawMessage
+	CreatedAt time.Time
+}
+
+// Position is a place in the event log. The log is read in the order the
+// logging transactions got their IDs, then by event ID, and only up to the
+// oldest transaction still running. IDs alone would not do: a transaction
+// can take an event ID and commit after one that took a higher ID, and a
+// reader following IDs would skip its event. Writers do not wait for each
+// other; instead a long running write transaction holds back the events
+// of every transaction that started after it.
+type Position struct {
+	TxID int64
+	ID   int64
+}
+
+// Position returns where event is in the log
+func (e Event) Position() Position {
+	return Position{TxID: e.TxID, ID: e.ID}
+}
+
+// After reports whether p comes after q in the log
+func (p Position) After(q Position) bool {
+	return p.TxID > q.TxID || (p.TxID == q.TxID && p.ID > q.ID)
+}
+
+// Broker fans events out to the subscribers on this replica
+type Broker struct {
+	db       *database.DB
+	listener *pq.Listener
+	last     Position // Last published; only touched by Run
+
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	closed      bool
+}
+
+// NewBroker listens for outbox notifications on a dedicated connection
+// opened with dsn. Only events committed after it starts are published.
+func NewBroker(ctx context.Context, db *database.DB, dsn string) (*Broker, error) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("Event listener connection problem", "event", event, "error", err)
+		}
+	})
+	if err := listener.Listen(webhook.NotifyChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to listen for events: %v", err)
+	}
+
+	broker := &Broker{db: db, listener: listener, subscribers: make(map[chan Event]struct{})}
+	// Transactions older than the oldest running one have ended, and their
+	// events are not published
+	err := db.QueryRowContext(ctx, "SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint").Scan(&broker.last.TxID)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to read event log position: %v", err)
+	}
+	return broker, nil
+}
+
+// Run publishes new events until ctx is canceled, then closes every
+// subscription
+func (b *Broker) Run(ctx context.Context) {
+	defer b.listener.Close()
+	defer b.Close()
+
+	// A notification can be lost while the listener reconnects, so the log
+	// is also checked every minute
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	// Set while committed events wait for an older transaction, which need
+	// not notify when it ends
+	var retry <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.listener.Notify:
+		case <-retry:
+		case <-ticker.C:
+			if err := b.listener.Ping(); err != nil {
+				slog.Warn("Event listener is not connected", "error", err)
+			}
+		}
+
+		retry = nil
+		held, err := b.publish(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("Failed to publish events", "error", err)
+		}
+		if held {
+			retry = time.After(heldRetry)
+		}
+	}
+}
+
+// publish sends every readable event after the last published one and
+// reports whether committed events are still held back
+func (b *Broker) publish(ctx context.Context) (bool, error) {
+	for {
+		events, err := Since(ctx, b.db, b.last, pageSize)
+		if err != nil {
+			return false, err
+		}
+		for _, event := range events {
+			b.broadcast(event)
+			b.last = event.Position()
+		}
+		if len(events) < pageSize {
+			return held(ctx, b.db, b.last)
+		}
+	}
+}
+
+// broadcast hands event to every subscriber, dropping those whose buffer
+// is full instead of letting one slow client hold up the rest
+func (b *Broker) broadcast(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns a channel of new events and a function that ends the
+// subscription. The channel is closed when the subscriber falls behind or
+// the broker stops.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends every subscription, so streams finish before the server
+// drains connections
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Since reads up to limit events after position after, in log order. It
+// stops at the oldest running transaction, so no event that commits later
+// can come before the last one returned.
+func Since(ctx context.Context, db *database.DB, after Position, limit int) ([]Event, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, tx_id, event_type, payload, created_at FROM outbox_events
+		WHERE (tx_id, id) > ($1, $2) AND tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+		ORDER BY tx_id, id LIMIT $3`, after.TxID, after.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var event Event
+		var data []byte
+		if err := rows.Scan(&event.ID, &event.TxID, &event.Type, &data, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %v", err)
+		}
+		event.Data = data
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// held reports whether committed events after position after are waiting
+// for an older transaction to end
+func held(ctx context.Context, db *database.DB, after Position) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM outbox_events WHERE (tx_id, id) > ($1, $2))",
+		after.TxID, after.ID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to read event log: %v", err)
+	}
+	return exists, nil
+}
+
+// Resume returns the position of the event with ID id, and false when it
+// is not in the log
+func Resume(ctx context.Context, db *database.DB, id int64) (Position, bool, error) {
+	position := Position{ID: id}
+	err := db.QueryRowContext(ctx, "SELECT tx_id FROM outbox_events WHERE id = $1", id).Scan(&position.TxID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Position{}, false, nil
+	}
+	if err != nil {
+		return Position{}, false, fmt.Errorf("failed to read event log: %v", err)
+	}
+	return position, true, nil
+}
+
+// Oldest returns the ID of the oldest event still in the log, or the ID the
+// next event gets when the log is empty. Events before it have been pruned.
+func Oldest(ctx context.Context, db *database.DB) (int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx, `SELECT COALESCE((SELECT MIN(id) FROM outbox_events),
+		(SELECT CASE WHEN is_called THEN last_value + 1 ELSE last_value END FROM outbox_events_id_seq))`).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read event log: %v", err)
+	}
+	return id, nil
+}
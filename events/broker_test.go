@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"go-crud-employee/database"
+	"go-crud-employee/database/dbtest"
+	"go-crud-employee/webhook"
+)
+
+func TestPositionAfter(t *testing.T) {
+	tests := []struct {
+		p, q Position
+		want bool
+	}{
+		{Position{TxID: 10, ID: 2}, Position{TxID: 10, ID: 1}, true},
+		{Position{TxID: 11, ID: 1}, Position{TxID: 10, ID: 5}, true},
+		{Position{TxID: 10, ID: 5}, Position{TxID: 11, ID: 1}, false},
+		{Position{TxID: 10, ID: 1}, Position{TxID: 10, ID: 1}, false},
+		{Position{TxID: 1, ID: 1}, Position{}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.p.After(tt.q); got != tt.want {
+			t.Errorf("%+v.After(%+v) = %t, want %t", tt.p, tt.q, got, tt.want)
+		}
+	}
+}
+
+// readIDs returns the IDs of the readable events, and whether others are
+// held back
+func readIDs(t *testing.T, db *database.DB) ([]int64, bool) {
+	t.Helper()
+
+	ctx := context.Background()
+	page, err := Since(ctx, db, Position{}, pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	last := Position{}
+	for _, event := range page {
+		ids = append(ids, event.ID)
+		last = event.Position()
+	}
+	waiting, err := held(ctx, db, last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids, waiting
+}
+
+func TestSinceStopsAtRunningTransactions(t *testing.T) {
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "outbox_events")
+	ctx := context.Background()
+	event := webhook.Event{Type: "employee.created", Data: map[string]int{"id": 1}}
+
+	// first starts before second but logs its event after it, so its event
+	// gets the higher ID and commits last
+	first, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Rollback()
+	if _, err := first.ExecContext(ctx, "SELECT pg_current_xact_id()"); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Rollback()
+	if err := webhook.Enqueue(ctx, second, event); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := webhook.Enqueue(ctx, first, event); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading ID 1 now would let a reader pass ID 2 before it commits
+	if ids, waiting := readIDs(t, db); len(ids) != 0 || !waiting {
+		t.Fatalf("while the first transaction runs: read %v, held %t; want nothing read and events held", ids, waiting)
+	}
+
+	if err := first.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	ids, waiting := readIDs(t, db)
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 1 || waiting {
+		t.Errorf("after commit: read %v, held %t; want [2 1] in transaction order and nothing held", ids, waiting)
+	}
+
+	position, found, err := Resume(ctx, db, 2)
+	if err != nil || !found || position.ID != 2 {
+		t.Fatalf("Resume(2) = %+v, %t, %v", position, found, err)
+	}
+	page, err := Since(ctx, db, position, pageSize)
+	if err != nil || len(page) != 1 || page[0].ID != 1 {
+		t.Errorf("Since the position of 2 = %+v, %v; want event 1", page, err)
+	}
+	if _, found, err := Resume(ctx, db, 99); found || err != nil {
+		t.Errorf("Resume(99) found %t, %v; want not found", found, err)
+	}
+}
@@ -2,10 +2,15 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
+	"go-crud-employee/apperror"
+	"go-crud-employee/auth"
 	"go-crud-employee/database"
+	"go-crud-employee/metrics"
+	"go-crud-employee/middleware"
 	"go-crud-employee/models"
 	"go-crud-employee/utils"
 
@@ -13,81 +18,68 @@ import (
 )
 
 type AuthHandler struct {
-	db         *database.DB
-	jwtManager *utils.JWTManager
+	db            *database.DB
+	jwtManager    *utils.JWTManager
+	revocations   *utils.RevocationList
+	authenticator auth.Authenticator
 }
 
-func NewAuthHandler(db *database.DB, jwtManager *utils.JWTManager) *AuthHandler {
+func NewAuthHandler(db *database.DB, jwtManager *utils.JWTManager, revocations *utils.RevocationList, authenticator auth.Authenticator) *AuthHandler {
 	return &AuthHandler{
-		db:         db,
-		jwtManager: jwtManager,
+		db:            db,
+		jwtManager:    jwtManager,
+		revocations:   revocations,
+		authenticator: authenticator,
 	}
 }
 
 // Login authenticates user and returns JWT token
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Invalid request data",
-			err.Error(),
-		))
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
 		return
 	}
 
-	// Find user by username
-	var user models.User
-	query := `SELECT id, username, email, password_hash, created_at, updated_at 
-			  FROM users WHERE username = $1`
-
-	err := h.db.QueryRow(query, req.Username).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
+	// Verify credentials against the configured backends
+	user, err := h.authenticator.Authenticate(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
-				"Authentication failed",
-				"Invalid username or password",
-			))
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			metrics.LoginFailed("password")
+			middleware.RespondError(c, apperror.ErrInvalidCredentials)
+			return
+		}
+		if errors.Is(err, auth.ErrAccountConflict) {
+			metrics.LoginFailed("password")
+			middleware.RespondError(c, apperror.ErrAccountConflict.WithCause(err))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Authentication error", err)
 		return
 	}
 
-	// Check password
-	if err := utils.CheckPassword(req.Password, user.PasswordHash); err != nil {
-		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
-			"Authentication failed",
-			"Invalid username or password",
-		))
+	// Reject disabled accounts
+	if !user.IsActive {
+		metrics.LoginFailed("password")
+		middleware.RespondError(c, apperror.ErrAccountDisabled)
 		return
 	}
 
-	// Generate JWT token
-	token, expiresAt, err := h.jwtManager.GenerateToken(&user)
+	// Generate JWT token bound to a new session
+	token, expiresAt, err := h.createSession(c, user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Token generation failed",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Token generation failed", err)
 		return
 	}
 
+	metrics.LoginSucceeded("password")
+
 	// Return login response
 	response := models.LoginResponse{
-		Token:     token,
-		User:      user.ToUserInfo(),
-		ExpiresAt: expiresAt,
+		Token:                 token,
+		User:                  user.ToUserInfo(),
+		ExpiresAt:             expiresAt,
+		PasswordResetRequired: user.PasswordResetRequired,
 	}
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(
@@ -99,67 +91,21 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // Register creates a new user account
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Invalid request data",
-			err.Error(),
-		))
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
 		return
 	}
 
 	// Validate password
 	if !utils.IsValidPassword(req.Password) {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Invalid password",
-			"Password must be at least 6 characters long",
-		))
-		return
-	}
-
-	// Check if username already exists
-	var count int
-	err := h.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = $1", req.Username).Scan(&count)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
-		return
-	}
-
-	if count > 0 {
-		c.JSON(http.StatusConflict, models.NewErrorResponse(
-			"Username already exists",
-			"Please choose a different username",
-		))
-		return
-	}
-
-	// Check if email already exists
-	err = h.db.QueryRow("SELECT COUNT(*) FROM users WHERE email = $1", req.Email).Scan(&count)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
-		return
-	}
-
-	if count > 0 {
-		c.JSON(http.StatusConflict, models.NewErrorResponse(
-			"Email already exists",
-			"Please use a different email address",
-		))
+		middleware.RespondError(c, apperror.ErrWeakPassword)
 		return
 	}
 
 	// Hash password
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Password hashing failed",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Password hashing failed", err)
 		return
 	}
 
@@ -167,22 +113,20 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	var user models.User
 	query := `INSERT INTO users (username, email, password_hash, created_at, updated_at) 
 			  VALUES ($1, $2, $3, $4, $5) 
-			  RETURNING id, username, email, created_at, updated_at`
+			  RETURNING id, username, email, role, created_at, updated_at`
 
 	now := time.Now()
-	err = h.db.QueryRow(query, req.Username, req.Email, hashedPassword, now, now).Scan(
+	err = h.db.QueryRowContext(c.Request.Context(), query, req.Username, req.Email, hashedPassword, now, now).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Failed to create user",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Failed to create user", err)
 		return
 	}
 
@@ -196,10 +140,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	claims, exists := c.Get("claims")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
-			"Unauthorized",
-			"User not authenticated",
-		))
+		middleware.RespondError(c, apperror.ErrUnauthenticated)
 		return
 	}
 
@@ -207,29 +148,24 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 
 	// Get user details from database
 	var user models.User
-	query := `SELECT id, username, email, created_at, updated_at 
+	query := `SELECT id, username, email, role, created_at, updated_at 
 			  FROM users WHERE id = $1`
 
-	err := h.db.QueryRow(query, userClaims.UserID).Scan(
+	err := h.db.QueryRowContext(c.Request.Context(), query, userClaims.UserID).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, models.NewErrorResponse(
-				"User not found",
-				"User does not exist",
-			))
+			middleware.RespondError(c, apperror.ErrUserNotFound)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Database error", err)
 		return
 	}
 
@@ -238,3 +174,56 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 		user.ToUserInfo(),
 	))
 }
+
+// ChangePassword replaces the current user's password and clears any pending reset
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		middleware.RespondError(c, apperror.ErrUnauthenticated)
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
+		return
+	}
+
+	if !utils.IsValidPassword(req.NewPassword) {
+		middleware.RespondError(c, apperror.ErrWeakPassword)
+		return
+	}
+
+	var passwordHash string
+	err := h.db.QueryRowContext(c.Request.Context(), "SELECT password_hash FROM users WHERE id = $1", userID).Scan(&passwordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			middleware.RespondError(c, apperror.ErrUserNotFound)
+			return
+		}
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	if err := utils.CheckPassword(req.CurrentPassword, passwordHash); err != nil {
+		middleware.RespondError(c, apperror.ErrInvalidCredentials.WithDetail("Current password is incorrect"))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		middleware.RespondServerError(c, "Password hashing failed", err)
+		return
+	}
+
+	query := `UPDATE users SET password_hash = $1, password_reset_required = false, updated_at = $2 WHERE id = $3`
+	if _, err := h.db.ExecContext(c.Request.Context(), query, hashedPassword, time.Now(), userID); err != nil {
+		middleware.RespondServerError(c, "Failed to change password", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Password changed successfully",
+		nil,
+	))
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-employee/auth"
+	"go-crud-employee/database/dbtest"
+	"go-crud-employee/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubAuthenticator fails every login with err
+type stubAuthenticator struct {
+	err error
+}
+
+func (a stubAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	return nil, a.err
+}
+
+func TestLoginErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"invalid credentials", auth.ErrInvalidCredentials, http.StatusUnauthorized},
+		{"account conflict", fmt.Errorf("%w: username %q belongs to a local account", auth.ErrAccountConflict, "rina"), http.StatusConflict},
+		{"backend failure", fmt.Errorf("failed to connect to LDAP server"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(nil, nil, nil, stubAuthenticator{err: tt.err})
+			router := gin.New()
+			router.POST("/api/v1/auth/login", handler.Login)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"username":"rina","password":"secret"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestRegisterConcurrentDuplicates(t *testing.T) {
+	db := dbtest.Open(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/register", NewAuthHandler(db, nil, nil, nil).Register)
+
+	const n = 10
+	tests := []struct {
+		name string
+		body func(i int) string
+		code string
+	}{
+		{"same username", func(i int) string {
+			return fmt.Sprintf(`{"username":"rina","email":"rina%d@example.com","password":"secret123"}`, i)
+		}, "DUPLICATE_USERNAME"},
+		{"same email", func(i int) string {
+			return fmt.Sprintf(`{"username":"rina%d","email":"rina@example.com","password":"secret123"}`, i)
+		}, "DUPLICATE_EMAIL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbtest.Truncate(t, db, "users")
+			checkOneCreated(t, postConcurrently(router, "/register", n, tt.body), tt.code)
+		})
+	}
+}
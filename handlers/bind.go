@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+var errTrailingData = errors.New("request body must contain a single JSON value")
+
+// bindStrictJSON works like ShouldBindJSON but rejects fields the request
+// struct does not declare, so typos such as "salery" fail loudly instead of
+// being ignored, and anything after the JSON value
+func bindStrictJSON(c *gin.Context, obj any) error {
+	return decodeStrictJSON(c.Request.Body, obj)
+}
+
+// decodeStrictJSON applies the rules of bindStrictJSON to any JSON input
+func decodeStrictJSON(r io.Reader, obj any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+	if err := decoder.Decode(new(json.RawMessage)); err != io.EOF {
+		return errTrailingData
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-employee/auth"
+	"go-crud-employee/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDecodeStrictJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		ok   bool
+	}{
+		{"valid", `{"username":"rina","password":"secret"}`, true},
+		{"trailing whitespace", "{\"username\":\"rina\",\"password\":\"secret\"}\n\t ", true},
+		{"unknown field", `{"username":"rina","password":"secret","role":"admin"}`, false},
+		{"second object", `{"username":"rina","password":"secret"}{"username":"admin"}`, false},
+		{"trailing brace", `{"username":"rina","password":"secret"}}`, false},
+		{"trailing garbage", `{"username":"rina","password":"secret"} x`, false},
+		{"missing field", `{"username":"rina"}`, false},
+		{"empty", ``, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.LoginRequest
+			if err := decodeStrictJSON(strings.NewReader(tt.body), &req); (err == nil) != tt.ok {
+				t.Errorf("decodeStrictJSON(%s) = %v, want ok %t", tt.body, err, tt.ok)
+			}
+		})
+	}
+
+	var req models.LoginRequest
+	if err := decodeStrictJSON(strings.NewReader(""), &req); !errors.Is(err, io.EOF) {
+		t.Errorf("empty body = %v, want io.EOF so optional bodies can be told apart", err)
+	}
+}
+
+// TestStrictBinding checks that auth, user and webhook endpoints reject
+// what the employee endpoints reject, before touching the database
+func TestStrictBinding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/login", NewAuthHandler(nil, nil, nil, stubAuthenticator{err: auth.ErrInvalidCredentials}).Login)
+	router.POST("/users", NewUserHandler(nil).CreateUser)
+	router.POST("/webhooks", NewWebhookHandler(nil, false).CreateWebhook)
+
+	tests := []struct {
+		path  string
+		body  string
+		field string
+	}{
+		{"/auth/login", `{"username":"rina","password":"secret","remember":true}`, "remember"},
+		{"/auth/login", `{"username":"rina","password":"secret"}{"username":"admin","password":"x"}`, ""},
+		{"/users", `{"username":"rina","email":"rina@example.com","password":"secret","is_admin":true}`, "is_admin"},
+		{"/webhooks", `{"url":"https://hooks.example.com","event_types":["employee.created"],"secrett":"x"}`, "secrett"},
+		{"/webhooks", `{"url":"https://hooks.example.com","event_types":["employee.created"]} []`, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST %s %s: status = %d, want %d", tt.path, tt.body, w.Code, http.StatusBadRequest)
+			continue
+		}
+		var body models.APIResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if tt.field != "" && !strings.Contains(w.Body.String(), `"field":"`+tt.field+`"`) {
+			t.Errorf("POST %s: response does not name the unknown field %s: %s", tt.path, tt.field, w.Body)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go-crud-employee/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// docsCSP relaxes the API's Content-Security-Policy just enough for the
+// bundled Swagger UI, which injects styles and inline SVG images
+const docsCSP = "default-src 'none'; script-src 'self'; style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; connect-src 'self'; frame-ancestors 'none'"
+
+type DocsHandler struct {
+	spec []byte
+	ui   http.Handler
+}
+
+func NewDocsHandler(doc *openapi.Document) (*DocsHandler, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &DocsHandler{
+		spec: spec,
+		ui:   http.StripPrefix("/docs", http.FileServerFS(openapi.UI)),
+	}, nil
+}
+
+// GetSpec serves the OpenAPI document
+func (h *DocsHandler) GetSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.spec)
+}
+
+// ServeUI serves Swagger UI for the OpenAPI document
+func (h *DocsHandler) ServeUI(c *gin.Context) {
+	c.Header("Content-Security-Policy", docsCSP)
+	h.ui.ServeHTTP(c.Writer, c.Request)
+}
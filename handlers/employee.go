@@ -8,77 +8,40 @@ import (
 	"strings"
 	"time"
 
+	"go-crud-employee/apperror"
 	"go-crud-employee/database"
+	"go-crud-employee/metrics"
+	"go-crud-employee/middleware"
 	"go-crud-employee/models"
+	"go-crud-employee/webhook"
 
 	"github.com/gin-gonic/gin"
 )
 
 type EmployeeHandler struct {
-	db *database.DB
+	db           *database.DB
+	maxBatchSize int
 }
 
-func NewEmployeeHandler(db *database.DB) *EmployeeHandler {
+func NewEmployeeHandler(db *database.DB, maxBatchSize int) *EmployeeHandler {
 	return &EmployeeHandler{
-		db: db,
+		db:           db,
+		maxBatchSize: maxBatchSize,
 	}
 }
 
 // CreateEmployee creates a new employee
 func (h *EmployeeHandler) CreateEmployee(c *gin.Context) {
 	var req models.CreateEmployeeRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Invalid request data",
-			err.Error(),
-		))
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
 		return
 	}
 
 	// Parse hire date
 	hireDate, err := time.Parse("2006-01-02", req.HireDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Invalid hire date format",
-			"Use YYYY-MM-DD format",
-		))
-		return
-	}
-
-	// Check if NIP already exists
-	var count int
-	err = h.db.QueryRow("SELECT COUNT(*) FROM employees WHERE nip = $1", req.NIP).Scan(&count)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
-		return
-	}
-
-	if count > 0 {
-		c.JSON(http.StatusConflict, models.NewErrorResponse(
-			"NIP already exists",
-			"Employee with this NIP already exists",
-		))
-		return
-	}
-
-	// Check if email already exists
-	err = h.db.QueryRow("SELECT COUNT(*) FROM employees WHERE email = $1", req.Email).Scan(&count)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
-		return
-	}
-
-	if count > 0 {
-		c.JSON(http.StatusConflict, models.NewErrorResponse(
-			"Email already exists",
-			"Employee with this email already exists",
-		))
+		middleware.RespondError(c, apperror.New(http.StatusBadRequest, apperror.CodeInvalidHireDate, "Invalid hire date format", "Use YYYY-MM-DD format"))
 		return
 	}
 
@@ -102,7 +65,15 @@ func (h *EmployeeHandler) CreateEmployee(c *gin.Context) {
 		salary.Valid = true
 	}
 
-	err = h.db.QueryRow(query, req.NIP, req.Name, req.Email, phone, req.Position, req.Department, salary, hireDate, true, now, now).Scan(
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		middleware.RespondServerError(c, "Failed to create employee", err)
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, req.NIP, req.Name, req.Email, phone, req.Position, req.Department, salary, hireDate, true, now, now).Scan(
 		&employee.ID,
 		&employee.NIP,
 		&employee.Name,
@@ -118,13 +89,23 @@ func (h *EmployeeHandler) CreateEmployee(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Failed to create employee",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Failed to create employee", err)
+		return
+	}
+
+	// The event is only sent if the employee is committed with it
+	event := webhook.EmployeeEvent(models.EventEmployeeCreated, employee.ToResponse())
+	if err := webhook.Enqueue(ctx, tx, event); err != nil {
+		middleware.RespondServerError(c, "Failed to create employee", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		middleware.RespondServerError(c, "Failed to create employee", err)
 		return
 	}
 
+	metrics.EmployeesCreated.Inc()
+
 	c.JSON(http.StatusCreated, models.NewSuccessResponse(
 		"Employee created successfully",
 		employee.ToResponse(),
@@ -135,10 +116,7 @@ func (h *EmployeeHandler) CreateEmployee(c *gin.Context) {
 func (h *EmployeeHandler) GetEmployees(c *gin.Context) {
 	var filter models.EmployeeFilter
 	if err := c.ShouldBindQuery(&filter); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Invalid query parameters",
-			err.Error(),
-		))
+		middleware.RespondError(c, apperror.InvalidRequest(err))
 		return
 	}
 
@@ -194,12 +172,9 @@ func (h *EmployeeHandler) GetEmployees(c *gin.Context) {
 
 	// Get total count
 	var total int
-	err := h.db.QueryRow(countQuery, args...).Scan(&total)
+	err := h.db.QueryRowContext(c.Request.Context(), countQuery, args...).Scan(&total)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Database error", err)
 		return
 	}
 
@@ -209,12 +184,9 @@ func (h *EmployeeHandler) GetEmployees(c *gin.Context) {
 	args = append(args, filter.Limit, filter.Offset)
 
 	// Execute query
-	rows, err := h.db.Query(baseQuery, args...)
+	rows, err := h.db.QueryContext(c.Request.Context(), baseQuery, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Database error", err)
 		return
 	}
 	defer rows.Close()
@@ -237,20 +209,14 @@ func (h *EmployeeHandler) GetEmployees(c *gin.Context) {
 			&emp.UpdatedAt,
 		)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-				"Database error",
-				err.Error(),
-			))
+			middleware.RespondServerError(c, "Database error", err)
 			return
 		}
 		employees = append(employees, emp.ToResponse())
 	}
 
 	if err = rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Database error", err)
 		return
 	}
 
@@ -272,10 +238,7 @@ func (h *EmployeeHandler) GetEmployee(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Invalid employee ID",
-			"Employee ID must be a number",
-		))
+		middleware.RespondError(c, apperror.ErrInvalidEmployeeID)
 		return
 	}
 
@@ -283,7 +246,7 @@ func (h *EmployeeHandler) GetEmployee(c *gin.Context) {
 	query := `SELECT id, nip, name, email, phone, position, department, salary, hire_date, is_active, created_at, updated_at 
 			  FROM employees WHERE id = $1`
 	
-	err = h.db.QueryRow(query, id).Scan(
+	err = h.db.QueryRowContext(c.Request.Context(), query, id).Scan(
 		&employee.ID,
 		&employee.NIP,
 		&employee.Name,
@@ -300,16 +263,10 @@ func (h *EmployeeHandler) GetEmployee(c *gin.Context) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, models.NewErrorResponse(
-				"Employee not found",
-				"Employee with the specified ID does not exist",
-			))
+			middleware.RespondError(c, apperror.ErrEmployeeNotFound)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Database error", err)
 		return
 	}
 
@@ -324,38 +281,34 @@ func (h *EmployeeHandler) UpdateEmployee(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Invalid employee ID",
-			"Employee ID must be a number",
-		))
+		middleware.RespondError(c, apperror.ErrInvalidEmployeeID)
 		return
 	}
 
 	var req models.UpdateEmployeeRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Invalid request data",
-			err.Error(),
-		))
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
 		return
 	}
 
-	// Check if employee exists
-	var exists int
-	err = h.db.QueryRow("SELECT COUNT(*) FROM employees WHERE id = $1", id).Scan(&exists)
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTx(ctx, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Failed to update employee", err)
 		return
 	}
+	defer tx.Rollback()
 
-	if exists == 0 {
-		c.JSON(http.StatusNotFound, models.NewErrorResponse(
-			"Employee not found",
-			"Employee with the specified ID does not exist",
-		))
+	// Check if employee exists; its current status tells whether this update
+	// deactivates it, and the lock keeps it true until commit
+	var wasActive bool
+	err = tx.QueryRowContext(ctx, "SELECT is_active FROM employees WHERE id = $1 FOR UPDATE", id).Scan(&wasActive)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			middleware.RespondError(c, apperror.ErrEmployeeNotFound)
+			return
+		}
+		middleware.RespondServerError(c, "Database error", err)
 		return
 	}
 
@@ -371,24 +324,6 @@ func (h *EmployeeHandler) UpdateEmployee(c *gin.Context) {
 	}
 
 	if req.Email != "" {
-		// Check if email already exists for other employees
-		var count int
-		err = h.db.QueryRow("SELECT COUNT(*) FROM employees WHERE email = $1 AND id != $2", req.Email, id).Scan(&count)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-				"Database error",
-				err.Error(),
-			))
-			return
-		}
-		if count > 0 {
-			c.JSON(http.StatusConflict, models.NewErrorResponse(
-				"Email already exists",
-				"Another employee with this email already exists",
-			))
-			return
-		}
-
 		setParts = append(setParts, fmt.Sprintf("email = $%d", argIndex))
 		args = append(args, req.Email)
 		argIndex++
@@ -425,10 +360,7 @@ func (h *EmployeeHandler) UpdateEmployee(c *gin.Context) {
 	}
 
 	if len(setParts) == 0 {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"No fields to update",
-			"At least one field must be provided for update",
-		))
+		middleware.RespondError(c, apperror.ErrNoFieldsToUpdate)
 		return
 	}
 
@@ -444,7 +376,7 @@ func (h *EmployeeHandler) UpdateEmployee(c *gin.Context) {
 		strings.Join(setParts, ", "), argIndex)
 
 	var employee models.Employee
-	err = h.db.QueryRow(query, args...).Scan(
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
 		&employee.ID,
 		&employee.NIP,
 		&employee.Name,
@@ -460,12 +392,23 @@ func (h *EmployeeHandler) UpdateEmployee(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Failed to update employee",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Failed to update employee", err)
+		return
+	}
+
+	event := webhook.EmployeeEvent(webhook.UpdateEventType(wasActive, employee.IsActive), employee.ToResponse())
+	if err := webhook.Enqueue(ctx, tx, event); err != nil {
+		middleware.RespondServerError(c, "Failed to update employee", err)
 		return
 	}
+	if err := tx.Commit(); err != nil {
+		middleware.RespondServerError(c, "Failed to update employee", err)
+		return
+	}
+
+	if wasActive && !employee.IsActive {
+		metrics.EmployeesDeactivated.Inc()
+	}
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(
 		"Employee updated successfully",
@@ -478,43 +421,59 @@ func (h *EmployeeHandler) DeleteEmployee(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Invalid employee ID",
-			"Employee ID must be a number",
-		))
+		middleware.RespondError(c, apperror.ErrInvalidEmployeeID)
 		return
 	}
 
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		middleware.RespondServerError(c, "Failed to delete employee", err)
+		return
+	}
+	defer tx.Rollback()
+
 	// Check if employee exists
 	var exists int
-	err = h.db.QueryRow("SELECT COUNT(*) FROM employees WHERE id = $1", id).Scan(&exists)
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM employees WHERE id = $1", id).Scan(&exists)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		middleware.RespondServerError(c, "Database error", err)
 		return
 	}
 
 	if exists == 0 {
-		c.JSON(http.StatusNotFound, models.NewErrorResponse(
-			"Employee not found",
-			"Employee with the specified ID does not exist",
-		))
+		middleware.RespondError(c, apperror.ErrEmployeeNotFound)
 		return
 	}
 
-	// Soft delete by setting is_active to false
-	query := `UPDATE employees SET is_active = false, updated_at = $1 WHERE id = $2`
-	_, err = h.db.Exec(query, time.Now(), id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"Failed to delete employee",
-			err.Error(),
-		))
+	// Soft delete by setting is_active to false; no row comes back when the
+	// employee was already inactive
+	query := `UPDATE employees SET is_active = false, updated_at = $1 WHERE id = $2 AND is_active = true
+			  RETURNING ` + employeeColumns
+	employee, err := scanEmployee(tx.QueryRowContext(ctx, query, time.Now(), id))
+	deactivated := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		middleware.RespondServerError(c, "Failed to delete employee", err)
+		return
+	}
+
+	if deactivated {
+		event := webhook.EmployeeEvent(models.EventEmployeeDeactivated, employee.ToResponse())
+		if err := webhook.Enqueue(ctx, tx, event); err != nil {
+			middleware.RespondServerError(c, "Failed to delete employee", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		middleware.RespondServerError(c, "Failed to delete employee", err)
 		return
 	}
 
+	// Only count employees that were still active
+	if deactivated {
+		metrics.EmployeesDeactivated.Inc()
+	}
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(
 		"Employee deleted successfully",
 		nil,
@@ -0,0 +1,616 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/database"
+	"go-crud-employee/metrics"
+	"go-crud-employee/middleware"
+	"go-crud-employee/models"
+	"go-crud-employee/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const employeeColumns = "id, nip, name, email, phone, position, department, salary, hire_date, is_active, created_at, updated_at"
+
+// batchInsertQuery creates employees from parallel arrays in one statement.
+// Rows that hit a unique constraint after the pre-check, because another
+// request created them meanwhile, are skipped and missing from RETURNING.
+const batchInsertQuery = `INSERT INTO employees (nip, name, email, phone, position, department, salary, hire_date, is_active, created_at, updated_at)
+	SELECT nip, name, email, NULLIF(phone, ''), position, department, NULLIF(salary, 0), hire_date, true, $9, $9
+	FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::numeric[], $8::date[])
+		AS v(nip, name, email, phone, position, department, salary, hire_date)
+	ON CONFLICT DO NOTHING
+	RETURNING ` + employeeColumns
+
+// batchUpdateQuery applies updates and deactivations in one statement. Empty
+// values leave a column unchanged, as in UpdateEmployee.
+const batchUpdateQuery = `UPDATE employees e SET
+		name = COALESCE(NULLIF(v.name, ''), e.name),
+		email = COALESCE(NULLIF(v.email, ''), e.email),
+		phone = COALESCE(NULLIF(v.phone, ''), e.phone),
+		position = COALESCE(NULLIF(v.position, ''), e.position),
+		department = COALESCE(NULLIF(v.department, ''), e.department),
+		salary = COALESCE(NULLIF(v.salary, 0), e.salary),
+		is_active = COALESCE(v.is_active, e.is_active),
+		updated_at = $9
+	FROM unnest($1::int[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::numeric[], $8::boolean[])
+		AS v(id, name, email, phone, position, department, salary, is_active)
+	WHERE e.id = v.id
+	RETURNING e.id, e.nip, e.name, e.email, e.phone, e.position, e.department, e.salary, e.hire_date, e.is_active, e.created_at, e.updated_at`
+
+type batchCreate struct {
+	index int
+	req   models.CreateEmployeeRequest
+}
+
+type batchUpdate struct {
+	index     int
+	op        models.BatchOperation
+	req       models.UpdateEmployeeRequest
+	id        int // Resolved from op.ID or op.NIP
+	wasActive bool
+}
+
+// employeeBatch holds the parsed operations and their results while a batch
+// request runs
+type employeeBatch struct {
+	mode    string
+	results []models.BatchResult
+	creates []*batchCreate
+	updates []*batchUpdate
+}
+
+// BatchEmployees applies create, update and deactivate operations with a
+// handful of set-based statements instead of queries per employee
+func (h *EmployeeHandler) BatchEmployees(c *gin.Context) {
+	var req models.BatchEmployeeRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
+		return
+	}
+	if len(req.Operations) > h.maxBatchSize {
+		middleware.RespondError(c, apperror.New(http.StatusBadRequest, apperror.CodeValidationFailed, "Too many operations",
+			fmt.Sprintf("A batch holds at most %d operations", h.maxBatchSize)))
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.BatchAtomic
+	}
+
+	batch := parseBatch(req)
+	ctx := c.Request.Context()
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		middleware.RespondServerError(c, "Failed to apply batch", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Every check runs before the first write, so an atomic batch that
+	// fails them changes nothing
+	steps := []func(context.Context, *database.Tx) error{batch.resolveTargets, batch.checkUnique, batch.insert, batch.update}
+	for _, step := range steps {
+		if batch.mode == models.BatchAtomic && batch.failed() > 0 {
+			break
+		}
+		if err := step(ctx, tx); err != nil {
+			middleware.RespondServerError(c, "Failed to apply batch", err)
+			return
+		}
+	}
+
+	if batch.mode == models.BatchAtomic && batch.failed() > 0 {
+		batch.respond(c)
+		return
+	}
+	if err := webhook.Enqueue(ctx, tx, batch.events()...); err != nil {
+		middleware.RespondServerError(c, "Failed to apply batch", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		middleware.RespondServerError(c, "Failed to apply batch", err)
+		return
+	}
+
+	for _, result := range batch.results {
+		switch {
+		case result.Error != nil:
+		case result.Op == models.BatchCreate:
+			metrics.EmployeesCreated.Inc()
+		case !result.Employee.IsActive:
+			if update := batch.updateAt(result.Index); update != nil && update.wasActive {
+				metrics.EmployeesDeactivated.Inc()
+			}
+		}
+	}
+	batch.respond(c)
+}
+
+// parseBatch validates every operation on its own and rejects operations
+// that clash with an earlier one in the same batch
+func parseBatch(req models.BatchEmployeeRequest) *employeeBatch {
+	batch := &employeeBatch{mode: req.Mode, results: make([]models.BatchResult, len(req.Operations))}
+	nips := make(map[string]bool)
+	emails := make(map[string]bool)
+	targets := make(map[string]bool)
+
+	for i, op := range req.Operations {
+		batch.results[i] = models.BatchResult{Index: i, Op: op.Op}
+
+		switch op.Op {
+		case models.BatchCreate:
+			create := &batchCreate{index: i}
+			if err := parseBatchData(op.Data, &create.req); err != nil {
+				batch.fail(i, err)
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", create.req.HireDate); err != nil {
+				batch.fail(i, apperror.New(http.StatusBadRequest, apperror.CodeInvalidHireDate, "Invalid hire date format", "Use YYYY-MM-DD format"))
+				continue
+			}
+			if nips[create.req.NIP] {
+				batch.fail(i, batchDuplicate(apperror.ErrDuplicateNIP))
+				continue
+			}
+			if emails[create.req.Email] {
+				batch.fail(i, batchDuplicate(apperror.ErrDuplicateEmail))
+				continue
+			}
+			nips[create.req.NIP] = true
+			emails[create.req.Email] = true
+			batch.creates = append(batch.creates, create)
+
+		case models.BatchUpdate, models.BatchDeactivate:
+			update := &batchUpdate{index: i, op: op}
+			if (op.ID == 0) == (op.NIP == "") {
+				batch.fail(i, apperror.New(http.StatusBadRequest, apperror.CodeValidationFailed, "Invalid request data",
+					"Exactly one of id and nip must identify the employee"))
+				continue
+			}
+			if op.Op == models.BatchUpdate {
+				if err := parseBatchData(op.Data, &update.req); err != nil {
+					batch.fail(i, err)
+					continue
+				}
+				if update.req == (models.UpdateEmployeeRequest{}) {
+					batch.fail(i, apperror.ErrNoFieldsToUpdate)
+					continue
+				}
+			} else {
+				if len(op.Data) > 0 {
+					batch.fail(i, apperror.New(http.StatusBadRequest, apperror.CodeValidationFailed, "Invalid request data",
+						"deactivate takes no data"))
+					continue
+				}
+				inactive := false
+				update.req.IsActive = &inactive
+			}
+
+			// The same employee may still be reached through both its id and
+			// its NIP; resolveTargets catches that
+			target := fmt.Sprintf("id:%d", op.ID)
+			if op.ID == 0 {
+				target = "nip:" + op.NIP
+			}
+			if targets[target] {
+				batch.fail(i, errBatchTargetRepeated)
+				continue
+			}
+			if update.req.Email != "" && emails[update.req.Email] {
+				batch.fail(i, batchDuplicate(apperror.ErrDuplicateEmail))
+				continue
+			}
+			targets[target] = true
+			if update.req.Email != "" {
+				emails[update.req.Email] = true
+			}
+			batch.updates = append(batch.updates, update)
+
+		default:
+			batch.fail(i, apperror.New(http.StatusBadRequest, apperror.CodeValidationFailed, "Invalid request data",
+				"op must be create, update or deactivate"))
+		}
+	}
+	return batch
+}
+
+var errBatchTargetRepeated = apperror.New(http.StatusBadRequest, apperror.CodeValidationFailed, "Invalid request data",
+	"Another operation in this batch already changes this employee")
+
+// batchDuplicate reports a value that an earlier operation in the batch uses
+func batchDuplicate(err *apperror.Error) *apperror.Error {
+	return err.WithDetail("An earlier operation in this batch uses the same value")
+}
+
+// parseBatchData decodes an operation's data with the rules of the single
+// employee endpoints
+func parseBatchData(data []byte, obj any) *apperror.Error {
+	if len(data) == 0 {
+		return apperror.New(http.StatusBadRequest, apperror.CodeValidationFailed, "Invalid request data", "data is required")
+	}
+	if err := decodeStrictJSON(bytes.NewReader(data), obj); err != nil {
+		return apperror.InvalidRequest(err)
+	}
+	return nil
+}
+
+// resolveTargets looks up and locks the employees that updates refer to, so
+// their previous status stays accurate until the batch commits
+func (b *employeeBatch) resolveTargets(ctx context.Context, tx *database.Tx) error {
+	pending := b.pendingUpdates()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(pending))
+	nips := make([]string, len(pending))
+	for i, update := range pending {
+		ids[i], nips[i] = int64(update.op.ID), update.op.NIP
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT v.ord, e.id, e.is_active
+		FROM unnest($1::int[], $2::text[]) WITH ORDINALITY AS v(id, nip, ord)
+		JOIN employees e ON e.id = COALESCE(NULLIF(v.id, 0), (SELECT id FROM employees WHERE nip = v.nip))
+		FOR UPDATE OF e`, pq.Array(ids), pq.Array(nips))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	found := make([]bool, len(pending))
+	for rows.Next() {
+		var ord, id int
+		var isActive bool
+		if err := rows.Scan(&ord, &id, &isActive); err != nil {
+			return err
+		}
+		found[ord-1] = true
+		pending[ord-1].id, pending[ord-1].wasActive = id, isActive
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool)
+	for i, update := range pending {
+		switch {
+		case !found[i]:
+			b.fail(update.index, apperror.ErrEmployeeNotFound)
+		case seen[update.id]:
+			b.fail(update.index, errBatchTargetRepeated)
+		default:
+			seen[update.id] = true
+		}
+	}
+	return nil
+}
+
+// checkUnique rejects NIPs and emails that other employees already use. It
+// runs before writing because one violation would fail a whole statement.
+func (b *employeeBatch) checkUnique(ctx context.Context, tx *database.Tx) error {
+	creates, updates := b.pendingCreates(), b.pendingUpdates()
+	var nips, emails []string
+	for _, create := range creates {
+		nips = append(nips, create.req.NIP)
+		emails = append(emails, create.req.Email)
+	}
+	for _, update := range updates {
+		if update.req.Email != "" {
+			emails = append(emails, update.req.Email)
+		}
+	}
+	if len(nips) == 0 && len(emails) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, nip, email FROM employees WHERE nip = ANY($1) OR email = ANY($2)",
+		pq.Array(nips), pq.Array(emails))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	nipOwners := make(map[string]int)
+	emailOwners := make(map[string]int)
+	for rows.Next() {
+		var id int
+		var nip, email string
+		if err := rows.Scan(&id, &nip, &email); err != nil {
+			return err
+		}
+		nipOwners[nip], emailOwners[email] = id, id
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	duplicateEmail := apperror.ErrDuplicateEmail.WithDetail("Employee with this email already exists")
+	for _, create := range creates {
+		if _, taken := nipOwners[create.req.NIP]; taken {
+			b.fail(create.index, apperror.ErrDuplicateNIP)
+		} else if _, taken := emailOwners[create.req.Email]; taken {
+			b.fail(create.index, duplicateEmail)
+		}
+	}
+	for _, update := range updates {
+		if owner, taken := emailOwners[update.req.Email]; taken && owner != update.id {
+			b.fail(update.index, duplicateEmail)
+		}
+	}
+	return nil
+}
+
+// insert creates the pending employees with a single INSERT
+func (b *employeeBatch) insert(ctx context.Context, tx *database.Tx) error {
+	pending := b.pendingCreates()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	n := len(pending)
+	nips, names, emails, phones := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	positions, departments, hireDates := make([]string, n), make([]string, n), make([]string, n)
+	salaries := make([]float64, n)
+	byNIP := make(map[string]*batchCreate, n)
+	for i, create := range pending {
+		req := create.req
+		nips[i], names[i], emails[i], phones[i] = req.NIP, req.Name, req.Email, req.Phone
+		positions[i], departments[i], hireDates[i] = req.Position, req.Department, req.HireDate
+		salaries[i] = max(req.Salary, 0)
+		byNIP[req.NIP] = create
+	}
+
+	indexes := make([]int, n)
+	for i, create := range pending {
+		indexes[i] = create.index
+	}
+	return b.apply(ctx, tx, "batch_insert", indexes, func() error {
+		rows, err := tx.QueryContext(ctx, batchInsertQuery, pq.Array(nips), pq.Array(names), pq.Array(emails), pq.Array(phones),
+			pq.Array(positions), pq.Array(departments), pq.Array(salaries), pq.Array(hireDates), time.Now())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			employee, err := scanEmployee(rows)
+			if err != nil {
+				return err
+			}
+			b.succeed(byNIP[employee.NIP].index, http.StatusCreated, employee)
+			delete(byNIP, employee.NIP)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, create := range byNIP {
+			b.fail(create.index, apperror.ErrDuplicateValue)
+		}
+		return nil
+	})
+}
+
+// update applies the pending updates and deactivations with a single UPDATE
+func (b *employeeBatch) update(ctx context.Context, tx *database.Tx) error {
+	pending := b.pendingUpdates()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	n := len(pending)
+	ids := make([]int64, n)
+	names, emails, phones, positions, departments := make([]string, n), make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	salaries := make([]float64, n)
+	actives := make([]sql.NullBool, n)
+	byID := make(map[int]*batchUpdate, n)
+	indexes := make([]int, n)
+	for i, update := range pending {
+		req := update.req
+		ids[i] = int64(update.id)
+		names[i], emails[i], phones[i], positions[i], departments[i] = req.Name, req.Email, req.Phone, req.Position, req.Department
+		salaries[i] = max(req.Salary, 0)
+		if req.IsActive != nil {
+			actives[i] = sql.NullBool{Bool: *req.IsActive, Valid: true}
+		}
+		byID[update.id] = update
+		indexes[i] = update.index
+	}
+
+	return b.apply(ctx, tx, "batch_update", indexes, func() error {
+		rows, err := tx.QueryContext(ctx, batchUpdateQuery, pq.Array(ids), pq.Array(names), pq.Array(emails), pq.Array(phones),
+			pq.Array(positions), pq.Array(departments), pq.Array(salaries), pq.Array(actives), time.Now())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			employee, err := scanEmployee(rows)
+			if err != nil {
+				return err
+			}
+			b.succeed(byID[employee.ID].index, http.StatusOK, employee)
+			delete(byID, employee.ID)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// Rows are locked since resolveTargets, so this is not expected
+		for _, update := range byID {
+			b.fail(update.index, apperror.ErrEmployeeNotFound)
+		}
+		return nil
+	})
+}
+
+// apply runs one write statement. A constraint violation, which a
+// concurrent request can still cause after the pre-checks, fails only the
+// operations in that statement; in best-effort mode a savepoint keeps the
+// rest of the transaction usable.
+func (b *employeeBatch) apply(ctx context.Context, tx *database.Tx, savepoint string, indexes []int, run func() error) error {
+	if b.mode == models.BatchBestEffort {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+			return err
+		}
+	}
+
+	err := run()
+	pgErr := apperror.FromPostgres(err)
+	if err != nil && pgErr == nil {
+		return err
+	}
+	if pgErr != nil {
+		for _, index := range indexes {
+			b.fail(index, pgErr)
+		}
+		if b.mode == models.BatchBestEffort {
+			_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *employeeBatch) pendingCreates() []*batchCreate {
+	var pending []*batchCreate
+	for _, create := range b.creates {
+		if b.results[create.index].Error == nil {
+			pending = append(pending, create)
+		}
+	}
+	return pending
+}
+
+func (b *employeeBatch) pendingUpdates() []*batchUpdate {
+	var pending []*batchUpdate
+	for _, update := range b.updates {
+		if b.results[update.index].Error == nil {
+			pending = append(pending, update)
+		}
+	}
+	return pending
+}
+
+func (b *employeeBatch) updateAt(index int) *batchUpdate {
+	for _, update := range b.updates {
+		if update.index == index {
+			return update
+		}
+	}
+	return nil
+}
+
+// events describes the applied operations for the webhook outbox.
+// Deactivating an employee that was already inactive changes nothing and
+// is left out.
+func (b *employeeBatch) events() []webhook.Event {
+	var events []webhook.Event
+	for _, result := range b.results {
+		if result.Error != nil {
+			continue
+		}
+		eventType := models.EventEmployeeCreated
+		if result.Op != models.BatchCreate {
+			wasActive := b.updateAt(result.Index).wasActive
+			if result.Op == models.BatchDeactivate && !wasActive {
+				continue
+			}
+			eventType = webhook.UpdateEventType(wasActive, result.Employee.IsActive)
+		}
+		events = append(events, webhook.EmployeeEvent(eventType, *result.Employee))
+	}
+	return events
+}
+
+func (b *employeeBatch) fail(index int, err *apperror.Error) {
+	b.results[index].Status = err.Status
+	b.results[index].Employee = nil
+	b.results[index].Error = &models.BatchError{
+		Code:    string(err.Code),
+		Message: err.Message,
+		Detail:  err.Detail,
+		Details: err.Fields,
+	}
+}
+
+func (b *employeeBatch) succeed(index, status int, employee models.Employee) {
+	response := employee.ToResponse()
+	b.results[index].Status = status
+	b.results[index].Employee = &response
+}
+
+func (b *employeeBatch) failed() int {
+	failed := 0
+	for _, result := range b.results {
+		if result.Error != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// respond reports every operation's result: 200 when all were applied, 207
+// when a best-effort batch applied some, and 422 when none were applied,
+// either because an atomic batch had a failure or every operation failed
+func (b *employeeBatch) respond(c *gin.Context) {
+	failed := b.failed()
+	response := models.BatchEmployeeResponse{
+		Mode:    b.mode,
+		Applied: len(b.results) - failed,
+		Failed:  failed,
+		Results: b.results,
+	}
+
+	switch {
+	case failed == 0:
+		c.JSON(http.StatusOK, models.NewSuccessResponse("Batch applied successfully", response))
+	case b.mode == models.BatchBestEffort && failed < len(b.results):
+		c.JSON(http.StatusMultiStatus, models.NewSuccessResponse("Batch partially applied", response))
+	default:
+		for i := range b.results {
+			if b.results[i].Error == nil {
+				b.fail(i, apperror.ErrNotApplied)
+			}
+		}
+		response.Applied, response.Failed = 0, len(b.results)
+
+		appErr := apperror.ErrBatchRejected
+		body := models.NewErrorResponse(appErr.Message, appErr.Detail)
+		body.Code = string(appErr.Code)
+		body.Data = response
+		body.RequestID = middleware.GetRequestID(c)
+		c.JSON(appErr.Status, body)
+	}
+}
+
+// scanEmployee reads a row selected with employeeColumns
+func scanEmployee(row interface{ Scan(...any) error }) (models.Employee, error) {
+	var employee models.Employee
+	err := row.Scan(
+		&employee.ID,
+		&employee.NIP,
+		&employee.Name,
+		&employee.Email,
+		&employee.Phone,
+		&employee.Position,
+		&employee.Department,
+		&employee.Salary,
+		&employee.HireDate,
+		&employee.IsActive,
+		&employee.CreatedAt,
+		&employee.UpdatedAt,
+	)
+	return employee, err
+}
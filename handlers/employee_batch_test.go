@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-crud-employee/database"
+	"go-crud-employee/database/dbtest"
+	"go-crud-employee/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseBatch(t *testing.T) {
+	create := func(nip, email string) models.BatchOperation {
+		data := `{"nip":"` + nip + `","name":"Rina","email":"` + email + `","position":"Engineer","department":"IT","hire_date":"2024-01-15"}`
+		return models.BatchOperation{Op: models.BatchCreate, Data: json.RawMessage(data)}
+	}
+
+	tests := []struct {
+		name string
+		ops  []models.BatchOperation
+		want []string // Error code of each operation, empty when it is pending
+	}{
+		{"valid", []models.BatchOperation{
+			create("EMP001", "rina@example.com"),
+			{Op: models.BatchUpdate, NIP: "EMP002", Data: json.RawMessage(`{"name":"Budi"}`)},
+			{Op: models.BatchDeactivate, ID: 3},
+		}, []string{"", "", ""}},
+		{"duplicates within the batch", []models.BatchOperation{
+			create("EMP001", "rina@example.com"),
+			create("EMP001", "other@example.com"),
+			create("EMP002", "rina@example.com"),
+			{Op: models.BatchUpdate, ID: 3, Data: json.RawMessage(`{"email":"rina@example.com"}`)},
+		}, []string{"", "DUPLICATE_NIP", "DUPLICATE_EMAIL", "DUPLICATE_EMAIL"}},
+		{"repeated target", []models.BatchOperation{
+			{Op: models.BatchUpdate, ID: 3, Data: json.RawMessage(`{"name":"Budi"}`)},
+			{Op: models.BatchDeactivate, ID: 3},
+		}, []string{"", "VALIDATION_FAILED"}},
+		{"invalid operations", []models.BatchOperation{
+			{Op: "delete", ID: 1},
+			{Op: models.BatchCreate},
+			{Op: models.BatchCreate, Data: json.RawMessage(`{"nip":"EMP001","name":"Rina","email":"rina@example.com","position":"Engineer","department":"IT","hire_date":"15-01-2024"}`)},
+			{Op: models.BatchCreate, Data: json.RawMessage(`{"nip":"EMP001","unknown":true}`)},
+			{Op: models.BatchUpdate, ID: 1, NIP: "EMP001", Data: json.RawMessage(`{"name":"Budi"}`)},
+			{Op: models.BatchUpdate, ID: 1, Data: json.RawMessage(`{}`)},
+			{Op: models.BatchDeactivate, ID: 2, Data: json.RawMessage(`{"name":"Budi"}`)},
+		}, []string{"VALIDATION_FAILED", "VALIDATION_FAILED", "INVALID_HIRE_DATE", "VALIDATION_FAILED", "VALIDATION_FAILED", "NO_FIELDS_TO_UPDATE", "VALIDATION_FAILED"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := parseBatch(models.BatchEmployeeRequest{Mode: models.BatchAtomic, Operations: tt.ops})
+			for i, want := range tt.want {
+				got := ""
+				if batch.results[i].Error != nil {
+					got = batch.results[i].Error.Code
+				}
+				if got != want {
+					t.Errorf("operation %d: error %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// batchFixture stores EMP001 and EMP003 and returns a router serving the
+// batch endpoint
+func batchFixture(t *testing.T) (*database.DB, *gin.Engine) {
+	t.Helper()
+
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "employees", "outbox_events")
+	_, err := db.Exec(`INSERT INTO employees (nip, name, email, position, department, hire_date) VALUES
+		('EMP001', 'Rina', 'rina@example.com', 'Engineer', 'IT', '2024-01-15'),
+		('EMP003', 'Sari', 'sari@example.com', 'Analyst', 'Finance', '2024-02-01')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/employees/batch", NewEmployeeHandler(db, 100).BatchEmployees)
+	return db, router
+}
+
+// postBatch sends operations in mode and returns the status and the batch
+// response
+func TestBatchRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	failure := &models.BatchError{Code: "EMPLOYEE_NOT_FOUND"}
+
+	tests := []struct {
+		name        string
+		mode        string
+		errors      []*models.BatchError
+		wantCode    int
+		wantApplied int
+	}{
+		{"atomic all applied", models.BatchAtomic, []*models.BatchError{nil, nil}, http.StatusOK, 2},
+		{"atomic one failed", models.BatchAtomic, []*models.BatchError{nil, failure}, http.StatusUnprocessableEntity, 0},
+		{"best effort all applied", models.BatchBestEffort, []*models.BatchError{nil, nil}, http.StatusOK, 2},
+		{"best effort some failed", models.BatchBestEffort, []*models.BatchError{nil, failure}, http.StatusMultiStatus, 1},
+		{"best effort all failed", models.BatchBestEffort, []*models.BatchError{failure, failure}, http.StatusUnprocessableEntity, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := &employeeBatch{mode: tt.mode}
+			for i, err := range tt.errors {
+				batch.results = append(batch.results, models.BatchResult{Index: i, Error: err})
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/employees/batch", nil)
+			batch.respond(c)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			var body struct {
+				Data models.BatchEmployeeResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid body %q: %v", w.Body, err)
+			}
+			if body.Data.Applied != tt.wantApplied || body.Data.Failed != len(tt.errors)-tt.wantApplied {
+				t.Errorf("applied %d, failed %d, want %d applied of %d", body.Data.Applied, body.Data.Failed, tt.wantApplied, len(tt.errors))
+			}
+		})
+	}
+}
+
+func postBatch(t *testing.T, router http.Handler, mode, operations string) (int, models.BatchEmployeeResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/employees/batch", strings.NewReader(`{"mode":"`+mode+`","operations":`+operations+`}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var body struct {
+		Data models.BatchEmployeeResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+	return w.Code, body.Data
+}
+
+// checkResults compares each result's status and error code with want
+func checkResults(t *testing.T, results []models.BatchResult, want []string) {
+	t.Helper()
+
+	if len(results) != len(want) {
+		t.Fatalf("%d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		got := http.StatusText(result.Status)
+		if result.Error != nil {
+			got += " " + result.Error.Code
+		}
+		if result.Index != i || got != want[i] {
+			t.Errorf("result %d: index %d, %q; want %q", i, result.Index, got, want[i])
+		}
+	}
+}
+
+// employeeState returns name and is_active of the employee with nip, or
+// "missing"
+func employeeState(t *testing.T, db *database.DB, nip string) string {
+	t.Helper()
+
+	var name string
+	var isActive bool
+	err := db.QueryRow("SELECT name, is_active FROM employees WHERE nip = $1", nip).Scan(&name, &isActive)
+	if err != nil {
+		return "missing"
+	}
+	if !isActive {
+		return name + " (inactive)"
+	}
+	return name
+}
+
+func outboxEvents(t *testing.T, db *database.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT event_type FROM outbox_events ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var types []string
+	for rows.Next() {
+		var eventType string
+		if err := rows.Scan(&eventType); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, eventType)
+	}
+	return types
+}
+
+func TestBatchAtomicRollsBack(t *testing.T) {
+	db, router := batchFixture(t)
+
+	status, response := postBatch(t, router, models.BatchAtomic, `[
+		{"op":"create","data":{"nip":"EMP002","name":"Budi","email":"budi@example.com","position":"Engineer","department":"IT","hire_date":"2024-03-01"}},
+		{"op":"update","nip":"EMP001","data":{"name":"Rina Wijaya"}},
+		{"op":"deactivate","nip":"EMP003"},
+		{"op":"create","data":{"nip":"EMP001","name":"Dewi","email":"dewi@example.com","position":"Engineer","department":"IT","hire_date":"2024-03-01"}}
+	]`)
+
+	if status != http.StatusUnprocessableEntity || response.Applied != 0 || response.Failed != 4 {
+		t.Errorf("status %d, applied %d, failed %d; want %d, 0, 4", status, response.Applied, response.Failed, http.StatusUnprocessableEntity)
+	}
+	checkResults(t, response.Results, []string{
+		"Failed Dependency NOT_APPLIED",
+		"Failed Dependency NOT_APPLIED",
+		"Failed Dependency NOT_APPLIED",
+		"Conflict DUPLICATE_NIP",
+	})
+
+	for nip, want := range map[string]string{"EMP001": "Rina", "EMP002": "missing", "EMP003": "Sari"} {
+		if got := employeeState(t, db, nip); got != want {
+			t.Errorf("%s = %s, want %s", nip, got, want)
+		}
+	}
+	if events := outboxEvents(t, db); len(events) != 0 {
+		t.Errorf("outbox holds %v, want no events", events)
+	}
+}
+
+func TestBatchBestEffortCommitsRest(t *testing.T) {
+	db, router := batchFixture(t)
+
+	status, response := postBatch(t, router, models.BatchBestEffort, `[
+		{"op":"create","data":{"nip":"EMP002","name":"Budi","email":"budi@example.com","position":"Engineer","department":"IT","hire_date":"2024-03-01"}},
+		{"op":"update","nip":"EMP001","data":{"name":"Rina Wijaya"}},
+		{"op":"deactivate","nip":"EMP003"},
+		{"op":"create","data":{"nip":"EMP001","name":"Dewi","email":"dewi@example.com","position":"Engineer","department":"IT","hire_date":"2024-03-01"}},
+		{"op":"update","id":9999,"data":{"name":"Nobody"}},
+		{"op":"create","data":{"nip":"EMP004","name":"Eko","email":"sari@example.com","position":"Engineer","department":"IT","hire_date":"2024-03-01"}}
+	]`)
+
+	if status != http.StatusMultiStatus || response.Applied != 3 || response.Failed != 3 {
+		t.Errorf("status %d, applied %d, failed %d; want %d, 3, 3", status, response.Applied, response.Failed, http.StatusMultiStatus)
+	}
+	checkResults(t, response.Results, []string{
+		"Created",
+		"OK",
+		"OK",
+		"Conflict DUPLICATE_NIP",
+		"Not Found EMPLOYEE_NOT_FOUND",
+		"Conflict DUPLICATE_EMAIL",
+	})
+	if created := response.Results[0].Employee; created == nil || created.NIP != "EMP002" || created.ID == 0 {
+		t.Errorf("created employee = %+v", created)
+	}
+
+	for nip, want := range map[string]string{"EMP001": "Rina Wijaya", "EMP002": "Budi", "EMP003": "Sari (inactive)", "EMP004": "missing"} {
+		if got := employeeState(t, db, nip); got != want {
+			t.Errorf("%s = %s, want %s", nip, got, want)
+		}
+	}
+	events := outboxEvents(t, db)
+	want := []string{models.EventEmployeeCreated, models.EventEmployeeUpdated, models.EventEmployeeDeactivated}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Errorf("outbox holds %v, want %v", events, want)
+	}
+
+	// Deactivating an inactive employee succeeds without an event
+	status, response = postBatch(t, router, models.BatchBestEffort, `[{"op":"deactivate","nip":"EMP003"}]`)
+	if status != http.StatusOK || response.Applied != 1 {
+		t.Errorf("repeated deactivate: status %d, applied %d; want %d, 1", status, response.Applied, http.StatusOK)
+	}
+	if events := outboxEvents(t, db); len(events) != len(want) {
+		t.Errorf("outbox holds %v after a no-op deactivate, want %v", events, want)
+	}
+}
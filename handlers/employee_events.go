@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/database"
+	"go-crud-employee/events"
+	"go-crud-employee/middleware"
+	"go-crud-employee/models"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// replayPage is how many logged events are read per query on resume
+const replayPage = 500
+
+type EventsHandler struct {
+	db        *database.DB
+	broker    *events.Broker
+	auth      *middleware.AuthMiddleware
+	heartbeat time.Duration
+}
+
+func NewEventsHandler(db *database.DB, broker *events.Broker, auth *middleware.AuthMiddleware, heartbeat time.Duration) *EventsHandler {
+	return &EventsHandler{
+		db:        db,
+		broker:    broker,
+		auth:      auth,
+		heartbeat: heartbeat,
+	}
+}
+
+// StreamEmployeeEvents streams employee changes as Server-Sent Events. A
+// client reconnecting with Last-Event-ID first gets the events it missed
+// from the log. Events carry the employee as GET /employees/:id shows it,
+// without the salary unless the caller is an admin, and the stream ends as soon as the caller's token, session
+// or role would no longer be accepted.
+func (h *EventsHandler) StreamEmployeeEvents(c *gin.Context) {
+	claims, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		middleware.RespondError(c, apperror.ErrUnauthenticated)
+		return
+	}
+
+	var lastID int64
+	resume := c.GetHeader("Last-Event-ID")
+	if resume != "" {
+		id, err := strconv.ParseInt(resume, 10, 64)
+		if err != nil || id < 0 {
+			middleware.RespondError(c, apperror.ErrInvalidEventID)
+			return
+		}
+		lastID = id
+	}
+
+	// Subscribe before reading the log, so nothing committed in between is
+	// missed; events already replayed are skipped below
+	live, unsubscribe := h.broker.Subscribe()
+	defer unsubscribe()
+
+	ctx := c.Request.Context()
+
+	// A stream outlives the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		slog.WarnContext(ctx, "Failed to clear write deadline for event stream", "error", err)
+	}
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("X-Accel-Buffering", "no") // Keep reverse proxies from buffering the stream
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	var last events.Position
+	if resume != "" {
+		position, pruned, err := h.resumePosition(ctx, lastID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to resume event stream", "error", err)
+			return
+		}
+		// The events after Last-Event-ID are gone; the client has to
+		// reload instead of applying changes on top of a stale copy
+		if pruned {
+			c.Render(-1, sse.Event{Event: "reset", Data: "events since Last-Event-ID have been pruned"})
+		}
+		last = position
+
+		for {
+			page, err := events.Since(ctx, h.db, last, replayPage)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to resume event stream", "error", err)
+				return
+			}
+			for _, event := range page {
+				sendEvent(c, event, claims.Role)
+				last = event.Position()
+			}
+			c.Writer.Flush()
+			if len(page) < replayPage {
+				break
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-live:
+			// Closed when the client fell behind or the server shuts down;
+			// it reconnects and resumes from the log
+			if !ok {
+				return
+			}
+			if !event.Position().After(last) {
+				continue
+			}
+			sendEvent(c, event, claims.Role)
+			c.Writer.Flush()
+			last = event.Position()
+		case <-heartbeat.C:
+			if err := h.auth.Recheck(ctx, claims); err != nil {
+				slog.InfoContext(ctx, "Ending event stream", "reason", err)
+				return
+			}
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// resumePosition returns where the event with ID lastID is in the log. An
+// event missing from it has been pruned, and so have the first events when
+// a client that has seen none finds the log starting later; the stream then
+// starts from the oldest event left and the second result is true.
+func (h *EventsHandler) resumePosition(ctx context.Context, lastID int64) (events.Position, bool, error) {
+	if lastID > 0 {
+		position, found, err := events.Resume(ctx, h.db, lastID)
+		return position, err == nil && !found, err
+	}
+	oldest, err := events.Oldest(ctx, h.db)
+	return events.Position{}, err == nil && oldest > 1, err
+}
+
+// sendEvent writes event for role. An event that cannot be written is
+// skipped, since ending the stream would fail the same way on every resume.
+func sendEvent(c *gin.Context, event events.Event, role string) {
+	if err := writeEvent(c, event, role); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Skipping event", "id", event.ID, "error", err)
+	}
+}
+
+// writeEvent writes event with the fields role may read, and with its log
+// ID so clients can resume after it
+func writeEvent(c *gin.Context, event events.Event, role string) error {
+	var employee models.EmployeeResponse
+	if err := json.Unmarshal(event.Data, &employee); err != nil {
+		return fmt.Errorf("failed to decode event: %v", err)
+	}
+	data, err := json.Marshal(employee.VisibleTo(role))
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %v", err)
+	}
+
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(event.ID, 10),
+		Event: event.Type,
+		Data:  data,
+	})
+	return nil
+}
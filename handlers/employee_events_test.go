@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud-employee/database"
+	"go-crud-employee/database/dbtest"
+	"go-crud-employee/events"
+	"go-crud-employee/middleware"
+	"go-crud-employee/models"
+	"go-crud-employee/utils"
+	"go-crud-employee/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseEvent is an event as a client parses it from the stream
+type sseEvent struct {
+	id, event, data string
+}
+
+// parseSSE splits body into events, skipping comments such as heartbeats
+func parseSSE(body string) []sseEvent {
+	var parsed []sseEvent
+	for _, block := range strings.Split(body, "\n\n") {
+		var event sseEvent
+		found := false
+		for _, line := range strings.Split(block, "\n") {
+			field, value, ok := strings.Cut(line, ":")
+			if !ok || field == "" {
+				continue
+			}
+			switch field {
+			case "id":
+				event.id = value
+			case "event":
+				event.event = value
+			case "data":
+				event.data = value
+			}
+			found = true
+		}
+		if found {
+			parsed = append(parsed, event)
+		}
+	}
+	return parsed
+}
+
+func TestWriteEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	data, _ := json.Marshal(models.EmployeeResponse{ID: 10, NIP: "EMP010", Name: "Rina", Salary: 15000000, IsActive: true})
+	event := events.Event{ID: 42, Type: models.EventEmployeeUpdated, Data: data}
+
+	tests := []struct {
+		role       string
+		wantSalary bool
+	}{
+		{models.RoleAdmin, true},
+		{models.RoleUser, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		if err := writeEvent(c, event, tt.role); err != nil {
+			t.Fatalf("role %q: %v", tt.role, err)
+		}
+
+		parsed := parseSSE(w.Body.String())
+		if len(parsed) != 1 || parsed[0].id != "42" || parsed[0].event != models.EventEmployeeUpdated {
+			t.Fatalf("role %q: wrote %q", tt.role, w.Body)
+		}
+		var employee map[string]any
+		if err := json.Unmarshal([]byte(parsed[0].data), &employee); err != nil {
+			t.Fatalf("role %q: data %q: %v", tt.role, parsed[0].data, err)
+		}
+		if _, ok := employee["salary"]; ok != tt.wantSalary {
+			t.Errorf("role %q: salary shown %t, want %t", tt.role, ok, tt.wantSalary)
+		}
+		if employee["nip"] != "EMP010" {
+			t.Errorf("role %q: data %v lost other fields", tt.role, employee)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	if err := writeEvent(c, events.Event{ID: 43, Type: models.EventEmployeeUpdated, Data: json.RawMessage(`[1,2]`)}, models.RoleAdmin); err == nil || w.Body.Len() != 0 {
+		t.Errorf("malformed event: error %v, wrote %q", err, w.Body)
+	}
+}
+
+// eventStream logs five events and returns a router streaming them to a
+// caller with role, checked every heartbeat, and that caller's user ID
+func eventStream(t *testing.T, role string, heartbeat time.Duration) (*database.DB, *gin.Engine, int) {
+	t.Helper()
+
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "users", "outbox_events")
+	ctx := context.Background()
+
+	var userID int
+	err := db.QueryRowContext(ctx, `INSERT INTO users (username, email, password_hash, role)
+		VALUES ('dashboard', 'dashboard@example.com', 'x', $1) RETURNING id`, role).Scan(&userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	var logged []webhook.Event
+	for i, nip := range []string{"EMP001", "EMP002", "EMP003", "EMP004", "EMP005"} {
+		logged = append(logged, webhook.EmployeeEvent(models.EventEmployeeCreated,
+			models.EmployeeResponse{ID: i + 1, NIP: nip, Name: "Rina", Salary: 15000000, IsActive: true}))
+	}
+	if err := webhook.Enqueue(ctx, tx, logged...); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	broker, err := events.NewBroker(ctx, db, os.Getenv("TEST_DATABASE_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	brokerCtx, stop := context.WithCancel(ctx)
+	go broker.Run(brokerCtx)
+	t.Cleanup(stop)
+
+	auth := middleware.NewAuthMiddleware(db, nil, utils.NewRevocationList(), nil)
+	handler := NewEventsHandler(db, broker, auth, heartbeat)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/employees/events", func(c *gin.Context) {
+		// A service account: no session, so only the user row is rechecked
+		c.Set("claims", &models.JWTClaims{UserID: userID, Username: "dashboard", Role: role})
+		c.Set("role", role)
+	}, handler.StreamEmployeeEvents)
+	return db, router, userID
+}
+
+// stream reads the events sent within a short while after connecting with
+// lastEventID
+func stream(t *testing.T, router http.Handler, lastEventID string) []sseEvent {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/employees/events", nil).WithContext(ctx)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	return parseSSE(w.Body.String())
+}
+
+// summarize lists "id event" and whether data shows the salary
+func summarize(t *testing.T, parsed []sseEvent) []string {
+	t.Helper()
+
+	var summary []string
+	for _, event := range parsed {
+		line := event.id + " " + event.event
+		if event.data != "" && event.event != "reset" {
+			var employee map[string]any
+			if err := json.Unmarshal([]byte(event.data), &employee); err != nil {
+				t.Fatalf("event %s: data %q: %v", event.id, event.data, err)
+			}
+			if _, ok := employee["salary"]; ok {
+				line += " salary"
+			}
+		}
+		summary = append(summary, strings.TrimSpace(line))
+	}
+	return summary
+}
+
+func TestStreamEmployeeEventsResume(t *testing.T) {
+	created := models.EventEmployeeCreated
+	tests := []struct {
+		name        string
+		role        string
+		lastEventID string
+		prune       bool
+		want        []string
+	}{
+		{"resume in order", models.RoleAdmin, "2", false, []string{"3 " + created + " salary", "4 " + created + " salary", "5 " + created + " salary"}},
+		{"nothing missed", models.RoleAdmin, "5", false, nil},
+		{"without Last-Event-ID", models.RoleAdmin, "", false, nil},
+		{"salary hidden", models.RoleUser, "3", false, []string{"4 " + created, "5 " + created}},
+		{"pruned", models.RoleAdmin, "1", true, []string{"reset", "4 " + created + " salary", "5 " + created + " salary"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, router, _ := eventStream(t, tt.role, time.Hour)
+			if tt.prune {
+				if _, err := db.Exec("DELETE FROM outbox_events WHERE id <= 3"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := summarize(t, stream(t, router, tt.lastEventID))
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamEmployeeEventsRechecksRole(t *testing.T) {
+	db, router, userID := eventStream(t, models.RoleAdmin, 50*time.Millisecond)
+
+	// Demoted after the token was issued: the next heartbeat ends the
+	// stream instead of the client's timeout
+	if _, err := db.Exec("UPDATE users SET role = $1 WHERE id = $2", models.RoleUser, userID); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	stream(t, router, "")
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("stream of a demoted caller lasted %v, want it ended at the first heartbeat", elapsed)
+	}
+}
+
+func TestStreamEmployeeEventsInvalidLastEventID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewEventsHandler(nil, nil, nil, time.Hour)
+	router := gin.New()
+	router.GET("/employees/events", func(c *gin.Context) {
+		c.Set("claims", &models.JWTClaims{UserID: 1, Role: models.RoleAdmin})
+	}, handler.StreamEmployeeEvents)
+
+	for _, id := range []string{"abc", "-1", "1.5"} {
+		req := httptest.NewRequest(http.MethodGet, "/employees/events", nil)
+		req.Header.Set("Last-Event-ID", id)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Last-Event-ID %q: status = %d, want %d", id, w.Code, http.StatusBadRequest)
+		}
+	}
+}
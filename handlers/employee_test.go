@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-crud-employee/database/dbtest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postConcurrently sends the n bodies to path at the same time
+func postConcurrently(router http.Handler, path string, n int, body func(i int) string) []*httptest.ResponseRecorder {
+	responses := make([]*httptest.ResponseRecorder, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body(i)))
+			req.Header.Set("Content-Type", "application/json")
+			<-start
+			responses[i] = httptest.NewRecorder()
+			router.ServeHTTP(responses[i], req)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return responses
+}
+
+// checkOneCreated fails unless exactly one response is 201 and the others
+// are 409 with code
+func checkOneCreated(t *testing.T, responses []*httptest.ResponseRecorder, code string) {
+	t.Helper()
+
+	created := 0
+	for _, w := range responses {
+		var body struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		switch {
+		case w.Code == http.StatusCreated:
+			created++
+		case w.Code != http.StatusConflict || body.Code != code:
+			t.Errorf("status = %d, code %q; want %d %s", w.Code, body.Code, http.StatusConflict, code)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d of %d creates succeeded, want 1", created, len(responses))
+	}
+}
+
+func TestCreateEmployeeConcurrentDuplicates(t *testing.T) {
+	db := dbtest.Open(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/employees", NewEmployeeHandler(db, 100).CreateEmployee)
+
+	const n = 10
+	tests := []struct {
+		name string
+		body func(i int) string
+		code string
+	}{
+		{"same nip", func(i int) string {
+			return fmt.Sprintf(`{"nip":"EMP001","name":"Rina","email":"rina%d@example.com","position":"Engineer","department":"IT","hire_date":"2024-01-15"}`, i)
+		}, "DUPLICATE_NIP"},
+		{"same email", func(i int) string {
+			return fmt.Sprintf(`{"nip":"EMP%03d","name":"Rina","email":"rina@example.com","position":"Engineer","department":"IT","hire_date":"2024-01-15"}`, i)
+		}, "DUPLICATE_EMAIL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbtest.Truncate(t, db, "employees", "outbox_events")
+			checkOneCreated(t, postConcurrently(router, "/employees", n, tt.body), tt.code)
+
+			var count int
+			if err := db.QueryRow("SELECT COUNT(*) FROM employees").Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 {
+				t.Errorf("%d employees stored, want 1", count)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-crud-employee/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{
+		registry: registry,
+	}
+}
+
+// Live reports that the process is running. It checks no dependencies so a
+// database outage does not make Kubernetes restart healthy pods.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": health.StatusUp,
+	})
+}
+
+// Ready runs the dependency checks. Degraded still accepts traffic (200);
+// down returns 503 so the instance is taken out of rotation.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.registry.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
+
+// Legacy serves /health with the body it had before the probes above: ok
+// while the server accepts traffic, 503 otherwise. Like Live it checks no
+// dependencies, so existing monitors see the same responses as before.
+func (h *HealthHandler) Legacy(c *gin.Context) {
+	if !h.registry.Ready() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "shutting_down",
+			"message": "Employee Management API is shutting down",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "ok",
+		"message": "Employee Management API is running",
+	})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud-employee/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+func healthRouter(ready bool, status health.Status) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	registry := health.NewRegistry(time.Second)
+	registry.SetReady(ready)
+	registry.Register("database", health.CheckerFunc(func(context.Context) (health.Status, string) {
+		return status, ""
+	}))
+
+	h := NewHealthHandler(registry)
+	router := gin.New()
+	router.GET("/healthz/live", h.Live)
+	router.GET("/healthz/ready", h.Ready)
+	router.GET("/health", h.Legacy)
+	return router
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		ready      bool
+		check      health.Status
+		wantCode   int
+		wantStatus string
+	}{
+		{"ready when up", "/healthz/ready", true, health.StatusUp, http.StatusOK, "up"},
+		{"ready when degraded", "/healthz/ready", true, health.StatusDegraded, http.StatusOK, "degraded"},
+		{"ready when down", "/healthz/ready", true, health.StatusDown, http.StatusServiceUnavailable, "down"},
+		{"ready while shutting down", "/healthz/ready", false, health.StatusUp, http.StatusServiceUnavailable, "down"},
+		{"live when down", "/healthz/live", true, health.StatusDown, http.StatusOK, "up"},
+		{"live while shutting down", "/healthz/live", false, health.StatusUp, http.StatusOK, "up"},
+		{"legacy when up", "/health", true, health.StatusUp, http.StatusOK, "ok"},
+		{"legacy ignores checks", "/health", true, health.StatusDown, http.StatusOK, "ok"},
+		{"legacy while shutting down", "/health", false, health.StatusUp, http.StatusServiceUnavailable, "shutting_down"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			healthRouter(tt.ready, tt.check).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			var body struct {
+				Status string `json:"status"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid body %q: %v", w.Body, err)
+			}
+			if body.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", body.Status, tt.wantStatus)
+			}
+		})
+	}
+}
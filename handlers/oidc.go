@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/auth"
+	"go-crud-employee/database"
+	"go-crud-employee/metrics"
+	"go-crud-employee/middleware"
+	"go-crud-employee/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateTTL bounds how long a user may take at the identity provider
+const oidcStateTTL = 10 * time.Minute
+
+// oidcBindingCookie ties a login to the browser that started it, so a
+// leaked callback URL cannot be redeemed elsewhere
+const oidcBindingCookie = "oidc_login"
+
+// oidcCookiePath limits the binding cookie to the login endpoints
+const oidcCookiePath = "/api/v1/auth/oidc/"
+
+type OIDCHandler struct {
+	db          *database.DB
+	client      *auth.OIDCClient
+	authHandler *AuthHandler
+}
+
+func NewOIDCHandler(db *database.DB, client *auth.OIDCClient, authHandler *AuthHandler) *OIDCHandler {
+	return &OIDCHandler{
+		db:          db,
+		client:      client,
+		authHandler: authHandler,
+	}
+}
+
+// Login starts the authorization code + PKCE flow by redirecting to the provider
+func (h *OIDCHandler) Login(c *gin.Context) {
+	state, nonce, codeVerifier, binding, err := auth.NewLoginState()
+	if err != nil {
+		middleware.RespondServerError(c, "Failed to start login", err)
+		return
+	}
+
+	// Drop abandoned logins while storing the new one
+	_, err = h.db.ExecContext(c.Request.Context(), "DELETE FROM oidc_login_states WHERE created_at < $1", time.Now().Add(-oidcStateTTL))
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	query := `INSERT INTO oidc_login_states (state, nonce, code_verifier, binding_hash, created_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := h.db.ExecContext(c.Request.Context(), query, state, nonce, codeVerifier, hashBinding(binding), time.Now()); err != nil {
+		middleware.RespondServerError(c, "Failed to start login", err)
+		return
+	}
+
+	// Lax, because the provider's redirect back is a cross-site navigation
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, binding, int(oidcStateTTL.Seconds()), oidcCookiePath, "", h.client.SecureCallback(), true)
+	c.Redirect(http.StatusFound, h.client.AuthCodeURL(state, nonce, codeVerifier))
+}
+
+// Callback completes the flow and issues the API's own JWT
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		middleware.RespondError(c, apperror.New(http.StatusUnauthorized, apperror.CodeAuthenticationFailed,
+			"Authentication failed",
+			fmt.Sprintf("%s: %s", errCode, c.Query("error_description")),
+		))
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		middleware.RespondError(c, apperror.New(http.StatusBadRequest, apperror.CodeInvalidCallback, "Invalid callback", "Missing state or code parameter"))
+		return
+	}
+
+	binding, err := c.Cookie(oidcBindingCookie)
+	if err != nil || binding == "" {
+		middleware.RespondError(c, apperror.New(http.StatusBadRequest, apperror.CodeInvalidCallback, "Invalid callback", "Login was not started in this browser"))
+		return
+	}
+	c.SetCookie(oidcBindingCookie, "", -1, oidcCookiePath, "", h.client.SecureCallback(), true)
+
+	// Each state can be redeemed exactly once, and only by the browser that
+	// started the login
+	var nonce, codeVerifier string
+	query := `DELETE FROM oidc_login_states WHERE state = $1 AND binding_hash = $2 AND created_at >= $3 RETURNING nonce, code_verifier`
+	err = h.db.QueryRowContext(c.Request.Context(), query, state, hashBinding(binding), time.Now().Add(-oidcStateTTL)).Scan(&nonce, &codeVerifier)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			middleware.RespondError(c, apperror.New(http.StatusBadRequest, apperror.CodeInvalidCallback, "Invalid callback", "Unknown or expired login state"))
+			return
+		}
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	identity, err := h.client.Exchange(c.Request.Context(), code, nonce, codeVerifier)
+	if err != nil {
+		metrics.LoginFailed("oidc")
+		middleware.RespondError(c, apperror.New(http.StatusUnauthorized, apperror.CodeAuthenticationFailed, "Authentication failed", "The identity provider response could not be verified").WithCause(err))
+		return
+	}
+
+	user, err := h.findOrProvisionUser(c.Request.Context(), identity)
+	if err != nil {
+		middleware.RespondServerError(c, "Failed to map user", err)
+		return
+	}
+
+	if user == nil {
+		metrics.LoginFailed("oidc")
+		middleware.RespondError(c, apperror.New(http.StatusForbidden, apperror.CodeAccountNotLinked, "Authentication failed", "No local account is linked to this identity"))
+		return
+	}
+
+	if !user.IsActive {
+		metrics.LoginFailed("oidc")
+		middleware.RespondError(c, apperror.ErrAccountDisabled)
+		return
+	}
+
+	token, expiresAt, err := h.authHandler.createSession(c, user)
+	if err != nil {
+		middleware.RespondServerError(c, "Token generation failed", err)
+		return
+	}
+
+	metrics.LoginSucceeded("oidc")
+
+	response := models.LoginResponse{
+		Token:     token,
+		User:      user.ToUserInfo(),
+		ExpiresAt: expiresAt,
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Login successful",
+		response,
+	))
+}
+
+// findOrProvisionUser resolves the local user for an identity. Users are
+// matched by issuer and subject, then linked by verified email, and finally
+// created when auto-provisioning is enabled. Returns nil when no user applies.
+// Existing users keep their role unless an admin mapping is configured.
+func (h *OIDCHandler) findOrProvisionUser(ctx context.Context, identity *auth.OIDCIdentity) (*models.User, error) {
+	role := h.client.MapRole(identity)
+	managedRole := sql.NullString{String: role, Valid: h.client.ManagesRoles()}
+
+	// Returning user: refresh role from the provider's claims
+	user, err := h.updateUser(ctx, `UPDATE users SET role = COALESCE($1, role), updated_at = $2
+		WHERE oidc_issuer = $3 AND oidc_subject = $4`,
+		managedRole, time.Now(), identity.Issuer, identity.Subject)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	// Existing local account: link it when the provider vouches for the email
+	if identity.Email != "" && identity.EmailVerified {
+		user, err = h.updateUser(ctx, `UPDATE users SET oidc_issuer = $1, oidc_subject = $2, role = COALESCE($3, role), updated_at = $4
+			WHERE email = $5 AND oidc_subject IS NULL`,
+			identity.Issuer, identity.Subject, managedRole, time.Now(), identity.Email)
+		if err != nil || user != nil {
+			return user, err
+		}
+	}
+
+	if !h.client.AutoProvision() || identity.Email == "" {
+		return nil, nil
+	}
+
+	username, err := h.availableUsername(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	// SSO users have no local password; an empty hash never matches
+	user = &models.User{}
+	query := `INSERT INTO users (username, email, password_hash, role, oidc_issuer, oidc_subject, created_at, updated_at)
+			  VALUES ($1, $2, '', $3, $4, $5, $6, $6)
+			  RETURNING id, username, email, role, is_active, password_reset_required, created_at, updated_at`
+
+	err = h.db.QueryRowContext(ctx, query, username, identity.Email, role, identity.Issuer, identity.Subject, time.Now()).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Role,
+		&user.IsActive,
+		&user.PasswordResetRequired,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, accountError("provision", err)
+	}
+
+	return user, nil
+}
+
+// updateUser runs an UPDATE on at most one user and returns it, or nil if no row matched
+func (h *OIDCHandler) updateUser(ctx context.Context, query string, args ...interface{}) (*models.User, error) {
+	var user models.User
+	query += " RETURNING id, username, email, role, is_active, password_reset_required, created_at, updated_at"
+
+	err := h.db.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Role,
+		&user.IsActive,
+		&user.PasswordResetRequired,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, accountError("update", err)
+	}
+
+	return &user, nil
+}
+
+// accountError reports a username or email that another local account
+// holds as a conflict, as the LDAP sync does, rather than a server error
+func accountError(action string, err error) error {
+	if apperror.FromPostgres(err) != nil {
+		return apperror.ErrAccountConflict.WithDetail("The username or email of this identity belongs to another local account").WithCause(err)
+	}
+	return fmt.Errorf("failed to %s user: %w", action, err)
+}
+
+// hashBinding is what is stored of the binding cookie, so a database read
+// does not reveal a value that completes a pending login
+func hashBinding(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return hex.EncodeToString(sum[:])
+}
+
+// availableUsername derives a unique username from the identity's claims
+func (h *OIDCHandler) availableUsername(ctx context.Context, identity *auth.OIDCIdentity) (string, error) {
+	username := identity.PreferredUsername
+	if username == "" {
+		username = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	if len(username) > 40 {
+		username = username[:40]
+	}
+
+	var count int
+	err := h.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE username = $1", username).Scan(&count)
+	if err != nil {
+		return "", fmt.Errorf("failed to check username: %w", err)
+	}
+
+	if count == 0 {
+		return username, nil
+	}
+
+	// Disambiguate with a stable suffix derived from the subject
+	sum := sha256.Sum256([]byte(identity.Issuer + identity.Subject))
+	return username + "-" + hex.EncodeToString(sum[:4]), nil
+}
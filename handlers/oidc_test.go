@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/auth"
+	"go-crud-employee/auth/oidctest"
+	"go-crud-employee/config"
+	"go-crud-employee/database"
+	"go-crud-employee/database/dbtest"
+	"go-crud-employee/models"
+	"go-crud-employee/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+func newOIDCTestRouter(t *testing.T, db *database.DB, adminValues ...string) (*gin.Engine, *oidctest.Provider) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	provider := oidctest.NewProvider(t, "employee-api")
+	client, err := auth.NewOIDCClient(context.Background(), config.OIDCConfig{
+		IssuerURL:     provider.URL,
+		ClientID:      provider.ClientID,
+		RedirectURL:   "http://localhost:8080/api/v1/auth/oidc/callback",
+		RoleClaim:     "groups",
+		AdminValues:   adminValues,
+		AutoProvision: true,
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCClient: %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret-with-enough-length-for-hs256"
+	cfg.JWT.Expiry = time.Hour
+	authHandler := NewAuthHandler(db, utils.NewJWTManager(cfg), utils.NewRevocationList(), auth.NewLocalAuthenticator(db))
+	handler := NewOIDCHandler(db, client, authHandler)
+
+	router := gin.New()
+	router.GET("/api/v1/auth/oidc/login", handler.Login)
+	router.GET("/api/v1/auth/oidc/callback", handler.Callback)
+	return router, provider
+}
+
+// startLogin runs the redirect to the provider and returns the
+// authorization URL and the binding cookie set on the browser
+func startLogin(t *testing.T, router *gin.Engine) (string, *http.Cookie) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d, body %s", w.Code, w.Body)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oidcBindingCookie {
+			if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+				t.Errorf("binding cookie is not HttpOnly and SameSite=Lax: %+v", cookie)
+			}
+			return w.Header().Get("Location"), cookie
+		}
+	}
+	t.Fatal("login did not set the binding cookie")
+	return "", nil
+}
+
+func callback(router *gin.Engine, authURL, code string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	parsed, _ := url.Parse(authURL)
+	query := url.Values{"state": {parsed.Query().Get("state")}, "code": {code}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOIDCCallbackRequiresBindingCookie(t *testing.T) {
+	router, _ := newOIDCTestRouter(t, nil)
+
+	w := callback(router, "http://idp/authorize?state=leaked", "leaked-code", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "users", "user_sessions", "oidc_login_states")
+	router, provider := newOIDCTestRouter(t, db)
+
+	// A local admin linked by verified email keeps the admin role when the
+	// provider's claims do not manage roles
+	_, err := db.ExecContext(context.Background(), `INSERT INTO users (username, email, password_hash, role)
+		VALUES ('rina', 'rina@example.com', 'x', 'admin')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]any{"sub": "rina-sub", "email": "rina@example.com", "email_verified": true}
+
+	t.Run("callback from another browser", func(t *testing.T) {
+		authURL, _ := startLogin(t, router)
+		_, otherCookie := startLogin(t, router)
+		code := provider.Authorize(t, authURL, claims)
+
+		if w := callback(router, authURL, code, otherCookie); w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+		}
+	})
+
+	t.Run("link keeps role", func(t *testing.T) {
+		authURL, cookie := startLogin(t, router)
+		code := provider.Authorize(t, authURL, claims)
+
+		w := callback(router, authURL, code, cookie)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var body struct {
+			Data models.LoginResponse `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Data.Token == "" || body.Data.User.Role != models.RoleAdmin {
+			t.Fatalf("login response = %+v, want a token for an admin", body.Data)
+		}
+
+		// The state cannot be redeemed again
+		if w := callback(router, authURL, code, cookie); w.Code != http.StatusBadRequest {
+			t.Fatalf("replayed callback status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestOIDCManagedRole(t *testing.T) {
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "users", "user_sessions", "oidc_login_states")
+	router, provider := newOIDCTestRouter(t, db, "hr-admins")
+
+	_, err := db.ExecContext(context.Background(), `INSERT INTO users (username, email, password_hash, role)
+		VALUES ('budi', 'budi@example.com', 'x', 'admin')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With an admin mapping, the provider's claims decide the role
+	authURL, cookie := startLogin(t, router)
+	code := provider.Authorize(t, authURL, map[string]any{
+		"sub": "budi-sub", "email": "budi@example.com", "email_verified": true, "groups": []string{"staff"},
+	})
+	if w := callback(router, authURL, code, cookie); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var role string
+	if err := db.QueryRowContext(context.Background(), "SELECT role FROM users WHERE username = 'budi'").Scan(&role); err != nil {
+		t.Fatal(err)
+	}
+	if role != models.RoleUser {
+		t.Fatalf("role = %q, want %q", role, models.RoleUser)
+	}
+}
+
+func TestOIDCProvisionConflict(t *testing.T) {
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "users", "user_sessions", "oidc_login_states")
+	router, provider := newOIDCTestRouter(t, db)
+
+	_, err := db.ExecContext(context.Background(), `INSERT INTO users (username, email, password_hash)
+		VALUES ('sari', 'sari@example.com', 'x')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unverified email is not linked, and provisioning it would take the
+	// local account's email
+	authURL, cookie := startLogin(t, router)
+	code := provider.Authorize(t, authURL, map[string]any{"sub": "sari-sub", "email": "sari@example.com", "email_verified": false})
+	w := callback(router, authURL, code, cookie)
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusConflict || body.Code != "ACCOUNT_CONFLICT" {
+		t.Fatalf("status = %d, code %q; want %d ACCOUNT_CONFLICT: %s", w.Code, body.Code, http.StatusConflict, w.Body)
+	}
+}
+
+func TestAccountError(t *testing.T) {
+	clash := &pq.Error{Code: "23505", Constraint: "users_email_key"}
+	if err := accountError("provision", clash); !errors.Is(err, apperror.ErrAccountConflict) || !errors.Is(err, clash) {
+		t.Errorf("accountError(unique violation) = %v, want an account conflict caused by it", err)
+	}
+
+	failure := errors.New("connection reset")
+	if err := accountError("provision", failure); errors.Is(err, apperror.ErrAccountConflict) || !errors.Is(err, failure) {
+		t.Errorf("accountError(%v) = %v, want it wrapped", failure, err)
+	}
+}
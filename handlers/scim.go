@@ -0,0 +1,612 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/database"
+	"go-crud-employee/metrics"
+	"go-crud-employee/models"
+	"go-crud-employee/utils"
+	"go-crud-employee/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+const scimContentType = "application/scim+json; charset=utf-8"
+
+// scimUserSelect loads a user together with its linked employee and the
+// SCIM id of that employee's manager
+const scimUserSelect = `SELECT u.id, u.username, u.email, u.is_active, COALESCE(u.external_id, ''), u.created_at, u.updated_at,
+		e.id, e.nip, e.name, e.phone, e.position, e.department, mu.id
+	FROM users u
+	LEFT JOIN employees e ON e.id = u.employee_id
+	LEFT JOIN users mu ON mu.employee_id = e.manager_id`
+
+// scimRequestError is a client error reported with a SCIM scimType
+type scimRequestError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimRequestError) Error() string {
+	return e.detail
+}
+
+type SCIMHandler struct {
+	db *database.DB
+}
+
+func NewSCIMHandler(db *database.DB) *SCIMHandler {
+	return &SCIMHandler{
+		db: db,
+	}
+}
+
+// GetUsers lists users with SCIM filtering and 1-based pagination
+func (h *SCIMHandler) GetUsers(c *gin.Context) {
+	startIndex, count := scimPage(c)
+
+	whereClause := ""
+	var args []interface{}
+	if filter := c.Query("filter"); filter != "" {
+		condition, filterArgs, err := parseSCIMFilter(filter, 1)
+		if err != nil {
+			scimErrorResponse(c, http.StatusBadRequest, "invalidFilter", err.Error())
+			return
+		}
+		whereClause = " WHERE " + condition
+		args = filterArgs
+	}
+
+	countQuery := `SELECT COUNT(*) FROM users u LEFT JOIN employees e ON e.id = u.employee_id` + whereClause
+	var total int
+	if err := h.db.QueryRowContext(c.Request.Context(), countQuery, args...).Scan(&total); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	query := scimUserSelect + whereClause +
+		fmt.Sprintf(" ORDER BY u.id LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, count, startIndex-1)
+
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	defer rows.Close()
+
+	resources := []models.SCIMUser{}
+	for rows.Next() {
+		user, err := scanSCIMUser(rows)
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+		setSCIMLocation(c, user)
+		resources = append(resources, *user)
+	}
+
+	if err = rows.Err(); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, models.SCIMListResponse{
+		Schemas:      []string{models.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// scimPage reads startIndex and count, falling back to the first page of
+// 100 for invalid values and capping count at 200
+func scimPage(c *gin.Context) (startIndex, count int) {
+	startIndex, err := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err = strconv.Atoi(c.DefaultQuery("count", "100"))
+	if err != nil || count < 0 {
+		count = 100
+	}
+	if count > 200 {
+		count = 200
+	}
+	return startIndex, count
+}
+
+// GetUser returns a single user resource
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	id, ok := parseSCIMID(c)
+	if !ok {
+		return
+	}
+
+	user, err := loadSCIMUser(c.Request.Context(), h.db, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setSCIMLocation(c, user)
+	scimJSON(c, http.StatusOK, user)
+}
+
+// CreateUser provisions a user and, with the enterprise extension, its employee record
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var req models.SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimErrorResponse(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	id, err := h.save(c.Request.Context(), 0, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	user, err := loadSCIMUser(c.Request.Context(), h.db, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setSCIMLocation(c, user)
+	c.Header("Location", user.Meta.Location)
+	scimJSON(c, http.StatusCreated, user)
+}
+
+// ReplaceUser handles PUT by replacing the user's attributes
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	id, ok := parseSCIMID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimErrorResponse(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	h.saveAndRespond(c, id, &req)
+}
+
+// PatchUser applies a PatchOp request to the user
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	id, ok := parseSCIMID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimErrorResponse(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	user, err := loadSCIMUser(c.Request.Context(), h.db, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	if err := applySCIMPatch(user, req.Operations); err != nil {
+		scimErrorResponse(c, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+
+	h.saveAndRespond(c, id, user)
+}
+
+// DeleteUser removes the user and deactivates the linked employee
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	id, ok := parseSCIMID(c)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var employeeID sql.NullInt64
+	err = tx.QueryRowContext(c.Request.Context(), "DELETE FROM users WHERE id = $1 RETURNING employee_id", id).Scan(&employeeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			scimErrorResponse(c, http.StatusNotFound, "", "User not found")
+			return
+		}
+		h.handleError(c, err)
+		return
+	}
+
+	// HR records are kept; the employee is only deactivated
+	var deactivated bool
+	if employeeID.Valid {
+		query := "UPDATE employees SET is_active = false, updated_at = $1 WHERE id = $2 AND is_active = true RETURNING " + employeeColumns
+		employee, err := scanEmployee(tx.QueryRowContext(c.Request.Context(), query, time.Now(), employeeID.Int64))
+		if err != nil && err != sql.ErrNoRows {
+			h.handleError(c, err)
+			return
+		}
+		if deactivated = err == nil; deactivated {
+			event := webhook.EmployeeEvent(models.EventEmployeeDeactivated, employee.ToResponse())
+			if err := webhook.Enqueue(c.Request.Context(), tx, event); err != nil {
+				h.handleError(c, err)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	if deactivated {
+		metrics.EmployeesDeactivated.Inc()
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ServiceProviderConfig advertises the supported SCIM features
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{models.SCIMSchemaServiceConfig},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": 200},
+		"changePassword": gin.H{"supported": true},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "Static bearer token configured with SCIM_TOKEN",
+		}},
+	})
+}
+
+// ResourceTypes lists the resource types served by this endpoint
+func (h *SCIMHandler) ResourceTypes(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":      []string{models.SCIMSchemaListResponse},
+		"totalResults": 1,
+		"Resources": []gin.H{{
+			"schemas":  []string{models.SCIMSchemaResourceType},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   models.SCIMSchemaUser,
+			"schemaExtensions": []gin.H{{
+				"schema":   models.SCIMSchemaEnterpriseUser,
+				"required": false,
+			}},
+		}},
+	})
+}
+
+func (h *SCIMHandler) saveAndRespond(c *gin.Context, id int, req *models.SCIMUser) {
+	if _, err := h.save(c.Request.Context(), id, req); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	user, err := loadSCIMUser(c.Request.Context(), h.db, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	setSCIMLocation(c, user)
+	scimJSON(c, http.StatusOK, user)
+}
+
+// save creates (id == 0) or replaces a user and its linked employee in one transaction
+func (h *SCIMHandler) save(ctx context.Context, id int, user *models.SCIMUser) (int, error) {
+	email := user.PrimaryEmail()
+	if user.UserName == "" {
+		return 0, &scimRequestError{http.StatusBadRequest, "invalidValue", "userName is required"}
+	}
+	if email == "" {
+		return 0, &scimRequestError{http.StatusBadRequest, "invalidValue", "an email address is required"}
+	}
+
+	active := true
+	if user.Active != nil {
+		active = *user.Active
+	}
+
+	// Provisioned users sign in via SSO unless the client sets a password
+	passwordHash := ""
+	if user.Password != "" {
+		hash, err := utils.HashPassword(user.Password)
+		if err != nil {
+			return 0, err
+		}
+		passwordHash = hash
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var linkedEmployeeID sql.NullInt64
+
+	if id == 0 {
+		query := `INSERT INTO users (username, email, password_hash, role, is_active, external_id, auth_source, created_at, updated_at)
+				  VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), 'scim', $7, $7)
+				  RETURNING id`
+		err = tx.QueryRowContext(ctx, query, user.UserName, email, passwordHash, models.RoleUser, active, user.ExternalID, now).Scan(&id)
+	} else {
+		query := `UPDATE users SET username = $1, email = $2, is_active = $3, external_id = NULLIF($4, ''),
+				  password_hash = CASE WHEN $5 = '' THEN password_hash ELSE $5 END, updated_at = $6
+				  WHERE id = $7
+				  RETURNING employee_id`
+		err = tx.QueryRowContext(ctx, query, user.UserName, email, active, user.ExternalID, passwordHash, now, id).Scan(&linkedEmployeeID)
+		if err == sql.ErrNoRows {
+			return 0, &scimRequestError{http.StatusNotFound, "", "User not found"}
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	employeeID, err := saveSCIMEmployee(ctx, tx, user, email, active, linkedEmployeeID, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if employeeID.Valid {
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET employee_id = $1 WHERE id = $2", employeeID.Int64, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// saveSCIMEmployee upserts the employee described by the enterprise extension.
+// Employees are matched by NIP (employeeNumber), or by the existing link when
+// no NIP is given. Empty attributes never overwrite stored values.
+func saveSCIMEmployee(ctx context.Context, tx *database.Tx, user *models.SCIMUser, email string, active bool, linkedEmployeeID sql.NullInt64, now time.Time) (sql.NullInt64, error) {
+	enterprise := user.Enterprise
+	if enterprise == nil {
+		enterprise = &models.SCIMEnterpriseUser{}
+	}
+
+	if enterprise.EmployeeNumber == "" && !linkedEmployeeID.Valid {
+		return sql.NullInt64{}, nil
+	}
+
+	var phone sql.NullString
+	if len(user.PhoneNumbers) > 0 && user.PhoneNumbers[0].Value != "" {
+		phone = sql.NullString{String: user.PhoneNumbers[0].Value, Valid: true}
+	}
+
+	// Resolve the manager's SCIM user id to their employee record
+	var managerID sql.NullInt64
+	if enterprise.Manager != nil {
+		managerUserID, err := strconv.Atoi(enterprise.Manager.Value)
+		if err != nil {
+			return sql.NullInt64{}, &scimRequestError{http.StatusBadRequest, "invalidValue", "manager.value must be a user id"}
+		}
+		err = tx.QueryRowContext(ctx, "SELECT employee_id FROM users WHERE id = $1", managerUserID).Scan(&managerID)
+		if err == sql.ErrNoRows {
+			return sql.NullInt64{}, &scimRequestError{http.StatusBadRequest, "invalidValue", "manager does not exist"}
+		}
+		if err != nil {
+			return sql.NullInt64{}, err
+		}
+	}
+
+	// The state before the change decides which webhook event it is
+	var wasActive bool
+	var err error
+	if enterprise.EmployeeNumber != "" {
+		err = tx.QueryRowContext(ctx, "SELECT is_active FROM employees WHERE nip = $1 FOR UPDATE", enterprise.EmployeeNumber).Scan(&wasActive)
+	} else {
+		err = tx.QueryRowContext(ctx, "SELECT is_active FROM employees WHERE id = $1 FOR UPDATE", linkedEmployeeID.Int64).Scan(&wasActive)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return sql.NullInt64{}, err
+	}
+	existed := err == nil
+
+	var employee models.Employee
+	if enterprise.EmployeeNumber != "" {
+		query := `INSERT INTO employees (nip, name, email, phone, position, department, manager_id, hire_date, is_active, created_at, updated_at)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_DATE, $8, $9, $9)
+				  ON CONFLICT (nip) DO UPDATE SET
+				      name = EXCLUDED.name,
+				      email = EXCLUDED.email,
+				      phone = COALESCE(EXCLUDED.phone, employees.phone),
+				      position = COALESCE(NULLIF(EXCLUDED.position, ''), employees.position),
+				      department = COALESCE(NULLIF(EXCLUDED.department, ''), employees.department),
+				      manager_id = EXCLUDED.manager_id,
+				      is_active = EXCLUDED.is_active,
+				      updated_at = EXCLUDED.updated_at
+				  RETURNING ` + employeeColumns
+		employee, err = scanEmployee(tx.QueryRowContext(ctx, query, enterprise.EmployeeNumber, user.FullName(), email, phone,
+			user.Title, enterprise.Department, managerID, active, now))
+	} else {
+		query := `UPDATE employees SET
+				      name = $1,
+				      email = $2,
+				      phone = COALESCE($3, phone),
+				      position = COALESCE(NULLIF($4, ''), position),
+				      department = COALESCE(NULLIF($5, ''), department),
+				      manager_id = $6,
+				      is_active = $7,
+				      updated_at = $8
+				  WHERE id = $9
+				  RETURNING ` + employeeColumns
+		employee, err = scanEmployee(tx.QueryRowContext(ctx, query, user.FullName(), email, phone, user.Title, enterprise.Department,
+			managerID, active, now, linkedEmployeeID.Int64))
+	}
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	employeeID := int64(employee.ID)
+	if managerID.Valid && managerID.Int64 == employeeID {
+		return sql.NullInt64{}, &scimRequestError{http.StatusBadRequest, "invalidValue", "an employee cannot be their own manager"}
+	}
+
+	eventType := models.EventEmployeeCreated
+	if existed {
+		eventType = webhook.UpdateEventType(wasActive, employee.IsActive)
+	}
+	if err := webhook.Enqueue(ctx, tx, webhook.EmployeeEvent(eventType, employee.ToResponse())); err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	return sql.NullInt64{Int64: employeeID, Valid: true}, nil
+}
+
+// handleError maps save/load errors onto SCIM error responses
+func (h *SCIMHandler) handleError(c *gin.Context, err error) {
+	var requestErr *scimRequestError
+	if errors.As(err, &requestErr) {
+		scimErrorResponse(c, requestErr.status, requestErr.scimType, requestErr.detail)
+		return
+	}
+
+	if pgErr := apperror.FromPostgres(err); pgErr != nil && pgErr.Status == http.StatusConflict {
+		scimErrorResponse(c, http.StatusConflict, "uniqueness", "A user or employee with the same identifier already exists")
+		return
+	}
+
+	switch {
+	case database.IsCanceled(c.Request.Context(), err):
+		scimErrorResponse(c, apperror.StatusClientClosedRequest, "", "The client closed the request")
+	case database.IsTimeout(err):
+		scimErrorResponse(c, http.StatusGatewayTimeout, "", "The database did not respond in time")
+	default:
+		// The cause stays in the logs, as for the rest of the API
+		slog.ErrorContext(c.Request.Context(), "SCIM request failed", "error", err)
+		scimErrorResponse(c, http.StatusInternalServerError, "", "An unexpected error occurred")
+	}
+}
+
+// loadSCIMUser loads one user resource by id
+func loadSCIMUser(ctx context.Context, db *database.DB, id int) (*models.SCIMUser, error) {
+	user, err := scanSCIMUser(db.QueryRowContext(ctx, scimUserSelect+" WHERE u.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, &scimRequestError{http.StatusNotFound, "", "User not found"}
+	}
+	return user, err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSCIMUser(row rowScanner) (*models.SCIMUser, error) {
+	var (
+		id               int
+		active           bool
+		createdAt        time.Time
+		updatedAt        time.Time
+		username, email  string
+		externalID       string
+		employeeID       sql.NullInt64
+		nip, name, phone sql.NullString
+		position, dept   sql.NullString
+		managerUserID    sql.NullInt64
+	)
+
+	err := row.Scan(&id, &username, &email, &active, &externalID, &createdAt, &updatedAt,
+		&employeeID, &nip, &name, &phone, &position, &dept, &managerUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.SCIMUser{
+		Schemas:    []string{models.SCIMSchemaUser},
+		ID:         strconv.Itoa(id),
+		ExternalID: externalID,
+		UserName:   username,
+		Active:     &active,
+		Emails:     []models.SCIMMultiValue{{Value: email, Type: "work", Primary: true}},
+		Meta: &models.SCIMMeta{
+			ResourceType: "User",
+			Created:      createdAt.UTC().Format(time.RFC3339),
+			LastModified: updatedAt.UTC().Format(time.RFC3339),
+		},
+	}
+
+	if employeeID.Valid {
+		user.Schemas = append(user.Schemas, models.SCIMSchemaEnterpriseUser)
+		user.Name = &models.SCIMName{Formatted: name.String}
+		user.DisplayName = name.String
+		user.Title = position.String
+		if phone.Valid && phone.String != "" {
+			user.PhoneNumbers = []models.SCIMMultiValue{{Value: phone.String, Type: "work", Primary: true}}
+		}
+		user.Enterprise = &models.SCIMEnterpriseUser{
+			EmployeeNumber: nip.String,
+			Department:     dept.String,
+		}
+		if managerUserID.Valid {
+			user.Enterprise.Manager = &models.SCIMManager{Value: strconv.FormatInt(managerUserID.Int64, 10)}
+		}
+	}
+
+	return user, nil
+}
+
+func setSCIMLocation(c *gin.Context, user *models.SCIMUser) {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	user.Meta.Location = fmt.Sprintf("%s://%s/scim/v2/Users/%s", scheme, c.Request.Host, user.ID)
+}
+
+func parseSCIMID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		scimErrorResponse(c, http.StatusNotFound, "", "User not found")
+		return 0, false
+	}
+	return id, true
+}
+
+func scimJSON(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(status, body)
+}
+
+func scimErrorResponse(c *gin.Context, status int, scimType, detail string) {
+	scimJSON(c, status, models.SCIMError{
+		Schemas:  []string{models.SCIMSchemaError},
+		Status:   strconv.Itoa(status),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+}
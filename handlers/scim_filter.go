@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crud-employee/models"
+)
+
+// scimAttribute describes how a filterable SCIM attribute maps onto SQL
+type scimAttribute struct {
+	column string
+	kind   string // "string", "bool" or "time"
+}
+
+// scimFilterAttributes lists the attributes supported in ?filter=, keyed by
+// lower-cased attribute path without the enterprise schema prefix
+var scimFilterAttributes = map[string]scimAttribute{
+	"id":                {"u.id::text", "string"},
+	"externalid":        {"u.external_id", "string"},
+	"username":          {"u.username", "string"},
+	"emails":            {"u.email", "string"},
+	"emails.value":      {"u.email", "string"},
+	"active":            {"u.is_active", "bool"},
+	"displayname":       {"e.name", "string"},
+	"name.formatted":    {"e.name", "string"},
+	"title":             {"e.position", "string"},
+	"employeenumber":    {"e.nip", "string"},
+	"department":        {"e.department", "string"},
+	"meta.created":      {"u.created_at", "time"},
+	"meta.lastmodified": {"u.updated_at", "time"},
+}
+
+// parseSCIMFilter translates a SCIM filter expression into a SQL condition.
+// Supported: attribute comparisons (eq, ne, co, sw, ew, gt, ge, lt, le, pr)
+// joined with "and" / "or", where "and" binds tighter. Grouping and "not"
+// are not supported.
+func parseSCIMFilter(filter string, argIndex int) (string, []interface{}, error) {
+	tokens, err := tokenizeSCIMFilter(filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var orGroups []string
+	var andTerms []string
+	var args []interface{}
+
+	for i := 0; ; {
+		attr := tokens[i]
+		if i+1 >= len(tokens) {
+			return "", nil, fmt.Errorf("incomplete filter near %q", attr)
+		}
+		op := strings.ToLower(tokens[i+1])
+		i += 2
+
+		var value string
+		if op != "pr" {
+			if i >= len(tokens) {
+				return "", nil, fmt.Errorf("missing value for %q", attr)
+			}
+			value = tokens[i]
+			i++
+		}
+
+		condition, conditionArgs, err := scimCondition(attr, op, value, argIndex)
+		if err != nil {
+			return "", nil, err
+		}
+		andTerms = append(andTerms, condition)
+		args = append(args, conditionArgs...)
+		argIndex += len(conditionArgs)
+
+		if i == len(tokens) {
+			break
+		}
+
+		switch strings.ToLower(tokens[i]) {
+		case "and":
+		case "or":
+			orGroups = append(orGroups, strings.Join(andTerms, " AND "))
+			andTerms = nil
+		default:
+			return "", nil, fmt.Errorf("unsupported filter operator %q", tokens[i])
+		}
+		i++
+		if i == len(tokens) {
+			return "", nil, fmt.Errorf("filter ends with a logical operator")
+		}
+	}
+
+	orGroups = append(orGroups, strings.Join(andTerms, " AND "))
+	return "(" + strings.Join(orGroups, ") OR (") + ")", args, nil
+}
+
+// scimCondition builds one comparison
+func scimCondition(attrPath, op, rawValue string, argIndex int) (string, []interface{}, error) {
+	path := strings.ToLower(strings.TrimPrefix(
+		strings.ToLower(attrPath), strings.ToLower(models.SCIMSchemaEnterpriseUser)+":"))
+
+	attr, ok := scimFilterAttributes[path]
+	if !ok {
+		return "", nil, fmt.Errorf("filtering on %q is not supported", attrPath)
+	}
+
+	if op == "pr" {
+		if attr.kind == "string" {
+			return fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", attr.column, attr.column), nil, nil
+		}
+		return fmt.Sprintf("%s IS NOT NULL", attr.column), nil, nil
+	}
+
+	placeholder := fmt.Sprintf("$%d", argIndex)
+
+	switch attr.kind {
+	case "bool":
+		value, err := strconv.ParseBool(rawValue)
+		if err != nil {
+			return "", nil, fmt.Errorf("%q expects a boolean", attrPath)
+		}
+		switch op {
+		case "eq":
+			return fmt.Sprintf("%s = %s", attr.column, placeholder), []interface{}{value}, nil
+		case "ne":
+			return fmt.Sprintf("%s <> %s", attr.column, placeholder), []interface{}{value}, nil
+		}
+
+	case "time":
+		value, err := time.Parse(time.RFC3339, unquoteSCIM(rawValue))
+		if err != nil {
+			return "", nil, fmt.Errorf("%q expects an RFC 3339 timestamp", attrPath)
+		}
+		if sqlOp, ok := map[string]string{"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<="}[op]; ok {
+			return fmt.Sprintf("%s %s %s", attr.column, sqlOp, placeholder), []interface{}{value}, nil
+		}
+
+	case "string":
+		value := unquoteSCIM(rawValue)
+		// SCIM string attributes used here are case-insensitive
+		column := fmt.Sprintf("LOWER(COALESCE(%s, ''))", attr.column)
+		lower := strings.ToLower(value)
+		switch op {
+		case "eq":
+			return fmt.Sprintf("%s = %s", column, placeholder), []interface{}{lower}, nil
+		case "ne":
+			return fmt.Sprintf("%s <> %s", column, placeholder), []interface{}{lower}, nil
+		case "co":
+			return fmt.Sprintf("%s LIKE %s", column, placeholder), []interface{}{"%" + escapeLike(lower) + "%"}, nil
+		case "sw":
+			return fmt.Sprintf("%s LIKE %s", column, placeholder), []interface{}{escapeLike(lower) + "%"}, nil
+		case "ew":
+			return fmt.Sprintf("%s LIKE %s", column, placeholder), []interface{}{"%" + escapeLike(lower)}, nil
+		case "gt", "ge", "lt", "le":
+			sqlOp := map[string]string{"gt": ">", "ge": ">=", "lt": "<", "le": "<="}[op]
+			return fmt.Sprintf("%s %s %s", column, sqlOp, placeholder), []interface{}{lower}, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("operator %q is not supported for %q", op, attrPath)
+}
+
+// tokenizeSCIMFilter splits a filter on whitespace, keeping quoted strings intact
+func tokenizeSCIMFilter(filter string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+	escaped := false
+
+	for _, r := range filter {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && inQuotes:
+			current.WriteRune(r)
+			escaped = true
+		case r == '"':
+			current.WriteRune(r)
+			inQuotes = !inQuotes
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		case (r == '(' || r == ')' || r == '[' || r == ']') && !inQuotes:
+			return nil, fmt.Errorf("grouping in filters is not supported")
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated string in filter")
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty filter")
+	}
+
+	return tokens, nil
+}
+
+// unquoteSCIM strips JSON string quoting from a filter value
+func unquoteSCIM(value string) string {
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	return strings.Trim(value, `"`)
+}
+
+// escapeLike escapes LIKE wildcards so values match literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go-crud-employee/models"
+)
+
+// applySCIMPatch applies PatchOp operations to a loaded user in place
+func applySCIMPatch(user *models.SCIMUser, operations []models.SCIMPatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return fmt.Errorf("unsupported patch op %q", operation.Op)
+		}
+		remove := op == "remove"
+
+		// Without a path the value is an object of attributes to set
+		if operation.Path == "" {
+			if remove {
+				return fmt.Errorf("remove requires a path")
+			}
+			attributes, ok := operation.Value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("patch value without a path must be an object")
+			}
+			for path, value := range attributes {
+				if err := setSCIMAttribute(user, path, value, false); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := setSCIMAttribute(user, operation.Path, operation.Value, remove); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setSCIMAttribute sets (or clears, when remove is true) one attribute path
+func setSCIMAttribute(user *models.SCIMUser, path string, value interface{}, remove bool) error {
+	lower := strings.ToLower(path)
+	enterprisePrefix := strings.ToLower(models.SCIMSchemaEnterpriseUser)
+
+	// The whole enterprise extension given as one object
+	if lower == enterprisePrefix {
+		if remove {
+			user.Enterprise = nil
+			return nil
+		}
+		attributes, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for name, attrValue := range attributes {
+			if err := setSCIMAttribute(user, models.SCIMSchemaEnterpriseUser+":"+name, attrValue, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(lower, enterprisePrefix+":") {
+		if user.Enterprise == nil {
+			user.Enterprise = &models.SCIMEnterpriseUser{}
+		}
+		return setSCIMEnterpriseAttribute(user.Enterprise, strings.TrimPrefix(lower, enterprisePrefix+":"), value, remove)
+	}
+
+	// Value filters such as emails[type eq "work"].value address the single
+	// value this API stores, so the filter itself is ignored
+	if open := strings.Index(lower, "["); open >= 0 {
+		closing := strings.Index(lower, "]")
+		if closing < open {
+			return fmt.Errorf("invalid path %q", path)
+		}
+		lower = lower[:open] + lower[closing+1:]
+	}
+
+	switch lower {
+	case "active":
+		if remove {
+			return fmt.Errorf("active cannot be removed")
+		}
+		active, err := scimBool(value)
+		if err != nil {
+			return fmt.Errorf("active: %v", err)
+		}
+		user.Active = &active
+	case "username":
+		if remove {
+			return fmt.Errorf("userName cannot be removed")
+		}
+		return scimStringInto(&user.UserName, value, "userName")
+	case "externalid":
+		return scimStringOrClear(&user.ExternalID, value, remove, "externalId")
+	case "displayname":
+		return scimStringOrClear(&user.DisplayName, value, remove, "displayName")
+	case "title":
+		return scimStringOrClear(&user.Title, value, remove, "title")
+	case "password":
+		return scimStringOrClear(&user.Password, value, remove, "password")
+	case "name":
+		if remove {
+			user.Name = nil
+			return nil
+		}
+		attributes, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("name must be an object")
+		}
+		for name, attrValue := range attributes {
+			if err := setSCIMAttribute(user, "name."+name, attrValue, false); err != nil {
+				return err
+			}
+		}
+	case "name.formatted", "name.givenname", "name.familyname":
+		if user.Name == nil {
+			user.Name = &models.SCIMName{}
+		}
+		target := map[string]*string{
+			"name.formatted":  &user.Name.Formatted,
+			"name.givenname":  &user.Name.GivenName,
+			"name.familyname": &user.Name.FamilyName,
+		}[lower]
+		return scimStringOrClear(target, value, remove, path)
+	case "emails", "emails.value":
+		values, err := scimMultiValues(value, remove)
+		if err != nil {
+			return fmt.Errorf("emails: %v", err)
+		}
+		user.Emails = values
+	case "phonenumbers", "phonenumbers.value":
+		values, err := scimMultiValues(value, remove)
+		if err != nil {
+			return fmt.Errorf("phoneNumbers: %v", err)
+		}
+		user.PhoneNumbers = values
+	default:
+		return fmt.Errorf("attribute %q is not supported", path)
+	}
+
+	return nil
+}
+
+func setSCIMEnterpriseAttribute(enterprise *models.SCIMEnterpriseUser, name string, value interface{}, remove bool) error {
+	switch name {
+	case "employeenumber":
+		return scimStringOrClear(&enterprise.EmployeeNumber, value, remove, "employeeNumber")
+	case "department":
+		return scimStringOrClear(&enterprise.Department, value, remove, "department")
+	case "manager", "manager.value":
+		if remove || value == nil {
+			enterprise.Manager = nil
+			return nil
+		}
+		// Accept either {"value": "<id>"} or the bare id
+		if attributes, ok := value.(map[string]interface{}); ok {
+			value = attributes["value"]
+		}
+		var managerID string
+		if err := scimStringInto(&managerID, value, "manager"); err != nil {
+			return err
+		}
+		if managerID == "" {
+			enterprise.Manager = nil
+			return nil
+		}
+		enterprise.Manager = &models.SCIMManager{Value: managerID}
+	default:
+		return fmt.Errorf("enterprise attribute %q is not supported", name)
+	}
+	return nil
+}
+
+// scimBool accepts JSON booleans and the "True"/"False" strings some clients send
+func scimBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.ToLower(v))
+	}
+	return false, fmt.Errorf("expected a boolean")
+}
+
+func scimStringInto(target *string, value interface{}, name string) error {
+	switch v := value.(type) {
+	case string:
+		*target = v
+	case float64:
+		*target = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Errorf("%s must be a string", name)
+	}
+	return nil
+}
+
+func scimStringOrClear(target *string, value interface{}, remove bool, name string) error {
+	if remove || value == nil {
+		*target = ""
+		return nil
+	}
+	return scimStringInto(target, value, name)
+}
+
+// scimMultiValues reads a multi-valued attribute given as a list of
+// objects, a single object or a bare string
+func scimMultiValues(value interface{}, remove bool) ([]models.SCIMMultiValue, error) {
+	if remove || value == nil {
+		return nil, nil
+	}
+
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	default:
+		items = []interface{}{v}
+	}
+
+	var values []models.SCIMMultiValue
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			values = append(values, models.SCIMMultiValue{Value: v, Primary: true})
+		case map[string]interface{}:
+			var multiValue models.SCIMMultiValue
+			if err := scimStringInto(&multiValue.Value, v["value"], "value"); err != nil {
+				return nil, err
+			}
+			multiValue.Type, _ = v["type"].(string)
+			multiValue.Primary, _ = v["primary"].(bool)
+			values = append(values, multiValue)
+		default:
+			return nil, fmt.Errorf("unexpected value")
+		}
+	}
+	return values, nil
+}
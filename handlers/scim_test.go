@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-crud-employee/database/dbtest"
+	"go-crud-employee/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+		args   []interface{}
+	}{
+		{
+			`userName eq "Rina"`,
+			`(LOWER(COALESCE(u.username, '')) = $3)`,
+			[]interface{}{"rina"},
+		},
+		{
+			`emails.value ew "@example.com" and active eq true`,
+			`(LOWER(COALESCE(u.email, '')) LIKE $3 AND u.is_active = $4)`,
+			[]interface{}{"%@example.com", true},
+		},
+		{
+			`title co "50%_off" or externalId pr`,
+			`(LOWER(COALESCE(e.position, '')) LIKE $3) OR ((u.external_id IS NOT NULL AND u.external_id <> ''))`,
+			[]interface{}{`%50\%\_off%`},
+		},
+		{
+			`meta.lastModified gt "2025-01-02T03:04:05Z"`,
+			`(u.updated_at > $3)`,
+			[]interface{}{time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		},
+		{
+			`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "EMP 001"`,
+			`(LOWER(COALESCE(e.nip, '')) = $3)`,
+			[]interface{}{"emp 001"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, args, err := parseSCIMFilter(tt.filter, 3)
+			if err != nil {
+				t.Fatalf("parseSCIMFilter: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("condition = %s, want %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseSCIMFilterErrors(t *testing.T) {
+	for _, filter := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName eq "rina" and`,
+		`userName eq "rina" xor active eq true`,
+		`(userName eq "rina")`,
+		`emails[type eq "work"]`,
+		`userName eq "rina`,
+		`password eq "secret"`,
+		`active co "tr"`,
+		`active eq maybe`,
+		`meta.created gt "yesterday"`,
+	} {
+		if _, _, err := parseSCIMFilter(filter, 1); err == nil {
+			t.Errorf("parseSCIMFilter(%q) succeeded, want an error", filter)
+		}
+	}
+}
+
+func TestApplySCIMPatch(t *testing.T) {
+	active := true
+	user := &models.SCIMUser{
+		UserName:    "rina",
+		DisplayName: "Rina",
+		Title:       "Engineer",
+		Active:      &active,
+		Emails:      []models.SCIMMultiValue{{Value: "rina@example.com", Primary: true}},
+	}
+
+	var operations []models.SCIMPatchOperation
+	err := json.Unmarshal([]byte(`[
+		{"op": "Replace", "path": "active", "value": "False"},
+		{"op": "add", "path": "emails[type eq \"work\"].value", "value": "rina.w@example.com"},
+		{"op": "remove", "path": "title"},
+		{"op": "replace", "value": {"displayName": "Rina W", "name.givenName": "Rina"}},
+		{"op": "add", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User", "value": {"department": "HR", "manager": {"value": "7"}}}
+	]`), &operations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applySCIMPatch(user, operations); err != nil {
+		t.Fatalf("applySCIMPatch: %v", err)
+	}
+
+	want := &models.SCIMUser{
+		UserName:    "rina",
+		DisplayName: "Rina W",
+		Active:      new(bool),
+		Name:        &models.SCIMName{GivenName: "Rina"},
+		Emails:      []models.SCIMMultiValue{{Value: "rina.w@example.com", Primary: true}},
+		Enterprise:  &models.SCIMEnterpriseUser{Department: "HR", Manager: &models.SCIMManager{Value: "7"}},
+	}
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("patched user = %+v, want %+v", user, want)
+	}
+}
+
+func TestApplySCIMPatchErrors(t *testing.T) {
+	tests := []models.SCIMPatchOperation{
+		{Op: "move", Path: "title", Value: "Lead"},
+		{Op: "remove"},
+		{Op: "replace", Value: "not an object"},
+		{Op: "remove", Path: "userName"},
+		{Op: "remove", Path: "active"},
+		{Op: "replace", Path: "active", Value: "sometimes"},
+		{Op: "replace", Path: "title", Value: []interface{}{"Lead"}},
+		{Op: "replace", Path: "nickName", Value: "Rin"},
+		{Op: "replace", Path: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:costCenter", Value: "X"},
+	}
+	for _, operation := range tests {
+		user := &models.SCIMUser{UserName: "rina"}
+		if err := applySCIMPatch(user, []models.SCIMPatchOperation{operation}); err == nil {
+			t.Errorf("applySCIMPatch(%+v) succeeded, want an error", operation)
+		}
+	}
+}
+
+func TestSCIMPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query      string
+		startIndex int
+		count      int
+	}{
+		{"", 1, 100},
+		{"?startIndex=3&count=10", 3, 10},
+		{"?startIndex=0&count=-1", 1, 100},
+		{"?startIndex=abc&count=abc", 1, 100},
+		{"?count=0", 1, 0},
+		{"?count=1000", 1, 200},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/scim/v2/Users"+tt.query, nil)
+
+		startIndex, count := scimPage(c)
+		if startIndex != tt.startIndex || count != tt.count {
+			t.Errorf("scimPage(%q) = %d, %d, want %d, %d", tt.query, startIndex, count, tt.startIndex, tt.count)
+		}
+	}
+}
+
+func TestSCIMListUsers(t *testing.T) {
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "users", "employees")
+	_, err := db.ExecContext(context.Background(), `INSERT INTO users (username, email, password_hash, role) VALUES
+		('andi', 'andi@example.com', 'x', 'user'),
+		('budi', 'budi@example.com', 'x', 'user'),
+		('bunga', 'bunga@example.com', 'x', 'user')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/scim/v2/Users", NewSCIMHandler(db).GetUsers)
+
+	tests := []struct {
+		query string
+		total int
+		start int
+		users []string
+	}{
+		{"", 3, 1, []string{"andi", "budi", "bunga"}},
+		{"?startIndex=2&count=1", 3, 2, []string{"budi"}},
+		{"?startIndex=4", 3, 4, nil},
+		{"?count=0", 3, 1, nil},
+		{`?filter=userName+sw+"B"&startIndex=2`, 2, 2, []string{"bunga"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/scim/v2/Users"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+
+			var list models.SCIMListResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+				t.Fatal(err)
+			}
+			var users []string
+			for _, user := range list.Resources {
+				users = append(users, user.UserName)
+			}
+			if list.TotalResults != tt.total || list.StartIndex != tt.start || list.ItemsPerPage != len(tt.users) || !reflect.DeepEqual(users, tt.users) {
+				t.Errorf("list = total %d, start %d, per page %d, users %v; want %d, %d, %d, %v",
+					list.TotalResults, list.StartIndex, list.ItemsPerPage, users, tt.total, tt.start, len(tt.users), tt.users)
+			}
+		})
+	}
+}
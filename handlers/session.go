@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/middleware"
+	"go-crud-employee/models"
+	"go-crud-employee/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createSession records a new login session and issues a JWT bound to it
+func (h *AuthHandler) createSession(c *gin.Context, user *models.User) (string, time.Time, error) {
+	sessionID, err := utils.GenerateSessionID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, expiresAt, err := h.jwtManager.GenerateToken(user, sessionID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	query := `INSERT INTO user_sessions (id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $5, $6)`
+
+	_, err = h.db.ExecContext(c.Request.Context(), query, sessionID, user.ID, c.Request.UserAgent(), c.ClientIP(), time.Now(), expiresAt)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create session: %v", err)
+	}
+
+	return token, expiresAt, nil
+}
+
+// GetSessions lists the current user's active sessions
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	claims, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		middleware.RespondError(c, apperror.ErrUnauthenticated)
+		return
+	}
+
+	query := `SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at
+			  FROM user_sessions
+			  WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+			  ORDER BY last_seen_at DESC`
+
+	rows, err := h.db.QueryContext(c.Request.Context(), query, claims.UserID, time.Now())
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+	defer rows.Close()
+
+	sessions := []models.SessionResponse{}
+	for rows.Next() {
+		var session models.Session
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			middleware.RespondServerError(c, "Database error", err)
+			return
+		}
+		sessions = append(sessions, session.ToResponse(claims.SessionID))
+	}
+
+	if err = rows.Err(); err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Sessions retrieved successfully",
+		sessions,
+	))
+}
+
+// RevokeSession signs out one of the current user's sessions
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	claims, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		middleware.RespondError(c, apperror.ErrUnauthenticated)
+		return
+	}
+
+	sessionID := c.Param("id")
+
+	// Only sessions owned by the caller can be revoked
+	var expiresAt time.Time
+	query := `UPDATE user_sessions SET revoked_at = $1
+			  WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL
+			  RETURNING expires_at`
+
+	err := h.db.QueryRowContext(c.Request.Context(), query, time.Now(), sessionID, claims.UserID).Scan(&expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			middleware.RespondError(c, apperror.ErrSessionNotFound)
+			return
+		}
+		middleware.RespondServerError(c, "Failed to revoke session", err)
+		return
+	}
+
+	// Take effect on this replica immediately; others pick it up on refresh
+	h.revocations.Revoke(sessionID, expiresAt)
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Session revoked successfully",
+		nil,
+	))
+}
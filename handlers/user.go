@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/database"
+	"go-crud-employee/middleware"
+	"go-crud-employee/models"
+	"go-crud-employee/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	db *database.DB
+}
+
+func NewUserHandler(db *database.DB) *UserHandler {
+	return &UserHandler{
+		db: db,
+	}
+}
+
+// CreateUser creates a new user account with the given role
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req models.CreateUserRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = models.RoleUser
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		middleware.RespondServerError(c, "Password hashing failed", err)
+		return
+	}
+
+	var user models.User
+	query := `INSERT INTO users (username, email, password_hash, role, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id, username, email, role, is_active, password_reset_required, created_at, updated_at`
+
+	now := time.Now()
+	err = h.db.QueryRowContext(c.Request.Context(), query, req.Username, req.Email, hashedPassword, req.Role, now, now).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Role,
+		&user.IsActive,
+		&user.PasswordResetRequired,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		middleware.RespondServerError(c, "Failed to create user", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		"User created successfully",
+		user.ToResponse(),
+	))
+}
+
+// GetUsers retrieves users with filtering and pagination
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	var filter models.UserFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
+		return
+	}
+
+	// Set default values
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	// Build query
+	var conditions []string
+	var args []interface{}
+	argIndex := 1
+
+	baseQuery := `SELECT id, username, email, role, is_active, password_reset_required, created_at, updated_at FROM users`
+	countQuery := `SELECT COUNT(*) FROM users`
+
+	if filter.Role != "" {
+		conditions = append(conditions, fmt.Sprintf("role = $%d", argIndex))
+		args = append(args, filter.Role)
+		argIndex++
+	}
+
+	if filter.IsActive != nil {
+		conditions = append(conditions, fmt.Sprintf("is_active = $%d", argIndex))
+		args = append(args, *filter.IsActive)
+		argIndex++
+	}
+
+	if filter.Search != "" {
+		searchPattern := "%" + filter.Search + "%"
+		conditions = append(conditions, fmt.Sprintf("(username ILIKE $%d OR email ILIKE $%d)", argIndex, argIndex+1))
+		args = append(args, searchPattern, searchPattern)
+		argIndex += 2
+	}
+
+	if len(conditions) > 0 {
+		whereClause := " WHERE " + strings.Join(conditions, " AND ")
+		baseQuery += whereClause
+		countQuery += whereClause
+	}
+
+	// Get total count
+	var total int
+	err := h.db.QueryRowContext(c.Request.Context(), countQuery, args...).Scan(&total)
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	// Add ordering and pagination
+	baseQuery += " ORDER BY created_at DESC"
+	baseQuery += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := h.db.QueryContext(c.Request.Context(), baseQuery, args...)
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+	defer rows.Close()
+
+	users := []models.UserResponse{}
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.Role,
+			&user.IsActive,
+			&user.PasswordResetRequired,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+		if err != nil {
+			middleware.RespondServerError(c, "Database error", err)
+			return
+		}
+		users = append(users, user.ToResponse())
+	}
+
+	if err = rows.Err(); err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	response := models.UserListResponse{
+		Users:  users,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Users retrieved successfully",
+		response,
+	))
+}
+
+// GetUser retrieves a single user by ID
+func (h *UserHandler) GetUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	query := `SELECT id, username, email, role, is_active, password_reset_required, created_at, updated_at
+			  FROM users WHERE id = $1`
+
+	err := h.db.QueryRowContext(c.Request.Context(), query, id).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Role,
+		&user.IsActive,
+		&user.PasswordResetRequired,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			middleware.RespondError(c, apperror.ErrUserNotFound)
+			return
+		}
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"User retrieved successfully",
+		user.ToResponse(),
+	))
+}
+
+// UpdateUser changes a user's email and/or role
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
+		return
+	}
+
+	// Admins cannot demote themselves and lock everyone out
+	if req.Role != "" && req.Role != models.RoleAdmin && isCurrentUser(c, id) {
+		middleware.RespondError(c, apperror.New(http.StatusBadRequest, apperror.CodeSelfModification, "Invalid role change", "You cannot remove your own admin role"))
+		return
+	}
+
+	var setParts []string
+	var args []interface{}
+	argIndex := 1
+
+	if req.Email != "" {
+		setParts = append(setParts, fmt.Sprintf("email = $%d", argIndex))
+		args = append(args, req.Email)
+		argIndex++
+	}
+
+	if req.Role != "" {
+		setParts = append(setParts, fmt.Sprintf("role = $%d", argIndex))
+		args = append(args, req.Role)
+		argIndex++
+	}
+
+	if len(setParts) == 0 {
+		middleware.RespondError(c, apperror.ErrNoFieldsToUpdate)
+		return
+	}
+
+	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
+	args = append(args, time.Now())
+	argIndex++
+
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE users SET %s WHERE id = $%d RETURNING id, username, email, role, is_active, password_reset_required, created_at, updated_at",
+		strings.Join(setParts, ", "), argIndex)
+
+	h.updateAndRespond(c, "User updated successfully", query, args...)
+}
+
+// UpdateUserStatus enables or disables a user account
+func (h *UserHandler) UpdateUserStatus(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UserStatusRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
+		return
+	}
+
+	if !*req.IsActive && isCurrentUser(c, id) {
+		middleware.RespondError(c, apperror.New(http.StatusBadRequest, apperror.CodeSelfModification, "Invalid status change", "You cannot disable your own account"))
+		return
+	}
+
+	query := `UPDATE users SET is_active = $1, updated_at = $2 WHERE id = $3
+			  RETURNING id, username, email, role, is_active, password_reset_required, created_at, updated_at`
+
+	message := "User enabled successfully"
+	if !*req.IsActive {
+		message = "User disabled successfully"
+	}
+
+	h.updateAndRespond(c, message, query, *req.IsActive, time.Now(), id)
+}
+
+// ResetUserPassword sets a temporary password and forces the user to change it on next login
+func (h *UserHandler) ResetUserPassword(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	// The request body is optional
+	var req models.ResetPasswordRequest
+	if err := bindStrictJSON(c, &req); err != nil && !errors.Is(err, io.EOF) {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
+		return
+	}
+
+	// Generate a temporary password when none was supplied
+	temporaryPassword := req.Password
+	generated := false
+	if temporaryPassword == "" {
+		password, err := utils.GenerateRandomPassword()
+		if err != nil {
+			middleware.RespondServerError(c, "Password generation failed", err)
+			return
+		}
+		temporaryPassword = password
+		generated = true
+	}
+
+	hashedPassword, err := utils.HashPassword(temporaryPassword)
+	if err != nil {
+		middleware.RespondServerError(c, "Password hashing failed", err)
+		return
+	}
+
+	var user models.User
+	query := `UPDATE users SET password_hash = $1, password_reset_required = true, updated_at = $2 WHERE id = $3
+			  RETURNING id, username, email, role, is_active, password_reset_required, created_at, updated_at`
+
+	err = h.db.QueryRowContext(c.Request.Context(), query, hashedPassword, time.Now(), id).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Role,
+		&user.IsActive,
+		&user.PasswordResetRequired,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			middleware.RespondError(c, apperror.ErrUserNotFound)
+			return
+		}
+		middleware.RespondServerError(c, "Failed to reset password", err)
+		return
+	}
+
+	response := models.ResetPasswordResponse{
+		User: user.ToResponse(),
+	}
+	// Only echo the password back when the server generated it
+	if generated {
+		response.TemporaryPassword = temporaryPassword
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Password reset successfully",
+		response,
+	))
+}
+
+// DeleteUser permanently removes a user account
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if isCurrentUser(c, id) {
+		middleware.RespondError(c, apperror.New(http.StatusBadRequest, apperror.CodeSelfModification, "Invalid deletion", "You cannot delete your own account"))
+		return
+	}
+
+	result, err := h.db.ExecContext(c.Request.Context(), "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		middleware.RespondServerError(c, "Failed to delete user", err)
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	if affected == 0 {
+		middleware.RespondError(c, apperror.ErrUserNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"User deleted successfully",
+		nil,
+	))
+}
+
+// updateAndRespond runs an UPDATE ... RETURNING query for a single user and writes the result
+func (h *UserHandler) updateAndRespond(c *gin.Context, message string, query string, args ...interface{}) {
+	var user models.User
+	err := h.db.QueryRowContext(c.Request.Context(), query, args...).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Role,
+		&user.IsActive,
+		&user.PasswordResetRequired,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			middleware.RespondError(c, apperror.ErrUserNotFound)
+			return
+		}
+		middleware.RespondServerError(c, "Failed to update user", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		message,
+		user.ToResponse(),
+	))
+}
+
+// parseUserID reads the :id path parameter, writing a 400 response when it is invalid
+func parseUserID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middleware.RespondError(c, apperror.ErrInvalidUserID)
+		return 0, false
+	}
+	return id, true
+}
+
+// isCurrentUser reports whether id belongs to the authenticated user
+func isCurrentUser(c *gin.Context, id int) bool {
+	userID, ok := middleware.GetUserID(c)
+	return ok && userID == id
+}
@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/database"
+	"go-crud-employee/middleware"
+	"go-crud-employee/models"
+	"go-crud-employee/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const webhookColumns = "id, url, secret, event_types, description, is_active, created_at, updated_at"
+
+const deliveryColumns = `d.id, d.subscription_id, d.event_id, e.event_type, d.status, d.attempts,
+	d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at`
+
+type WebhookHandler struct {
+	db           *database.DB
+	requireHTTPS bool
+}
+
+func NewWebhookHandler(db *database.DB, requireHTTPS bool) *WebhookHandler {
+	return &WebhookHandler{
+		db:           db,
+		requireHTTPS: requireHTTPS,
+	}
+}
+
+// CreateWebhook subscribes a URL to employee events. The signing secret is
+// only returned here, so a generated one must be saved by the caller.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
+		return
+	}
+
+	if err := h.checkURL(req.URL); err != nil {
+		middleware.RespondError(c, err)
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := webhook.GenerateSecret()
+		if err != nil {
+			middleware.RespondServerError(c, "Secret generation failed", err)
+			return
+		}
+		secret = generated
+	}
+
+	query := `INSERT INTO webhook_subscriptions (url, secret, event_types, description)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING ` + webhookColumns
+
+	subscription, err := scanWebhook(h.db.QueryRowContext(c.Request.Context(), query,
+		req.URL, secret, pq.Array(req.EventTypes), req.Description))
+	if err != nil {
+		middleware.RespondServerError(c, "Failed to create webhook", err)
+		return
+	}
+
+	response := subscription.ToResponse()
+	response.Secret = subscription.Secret
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		"Webhook created successfully",
+		response,
+	))
+}
+
+// GetWebhooks lists all webhook subscriptions
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	rows, err := h.db.QueryContext(c.Request.Context(), "SELECT "+webhookColumns+" FROM webhook_subscriptions ORDER BY id")
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+	defer rows.Close()
+
+	webhooks := []models.WebhookResponse{}
+	for rows.Next() {
+		subscription, err := scanWebhook(rows)
+		if err != nil {
+			middleware.RespondServerError(c, "Database error", err)
+			return
+		}
+		webhooks = append(webhooks, subscription.ToResponse())
+	}
+
+	if err = rows.Err(); err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Webhooks retrieved successfully",
+		webhooks,
+	))
+}
+
+// GetWebhook retrieves a single webhook subscription by ID
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := scanWebhook(h.db.QueryRowContext(c.Request.Context(),
+		"SELECT "+webhookColumns+" FROM webhook_subscriptions WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			middleware.RespondError(c, apperror.ErrWebhookNotFound)
+			return
+		}
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Webhook retrieved successfully",
+		subscription.ToResponse(),
+	))
+}
+
+// UpdateWebhook changes a subscription. A new secret takes effect for the
+// next attempt, including retries of events signed with the old one.
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
+		return
+	}
+
+	var setParts []string
+	var args []interface{}
+	argIndex := 1
+
+	if req.URL != "" {
+		if err := h.checkURL(req.URL); err != nil {
+			middleware.RespondError(c, err)
+			return
+		}
+		setParts = append(setParts, fmt.Sprintf("url = $%d", argIndex))
+		args = append(args, req.URL)
+		argIndex++
+	}
+
+	if len(req.EventTypes) > 0 {
+		setParts = append(setParts, fmt.Sprintf("event_types = $%d", argIndex))
+		args = append(args, pq.Array(req.EventTypes))
+		argIndex++
+	}
+
+	if req.Secret != "" {
+		setParts = append(setParts, fmt.Sprintf("secret = $%d", argIndex))
+		args = append(args, req.Secret)
+		argIndex++
+	}
+
+	if req.Description != nil {
+		setParts = append(setParts, fmt.Sprintf("description = $%d", argIndex))
+		args = append(args, *req.Description)
+		argIndex++
+	}
+
+	if req.IsActive != nil {
+		setParts = append(setParts, fmt.Sprintf("is_active = $%d", argIndex))
+		args = append(args, *req.IsActive)
+		argIndex++
+	}
+
+	if len(setParts) == 0 {
+		middleware.RespondError(c, apperror.ErrNoFieldsToUpdate)
+		return
+	}
+
+	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
+	args = append(args, time.Now())
+	argIndex++
+
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE webhook_subscriptions SET %s WHERE id = $%d RETURNING %s",
+		strings.Join(setParts, ", "), argIndex, webhookColumns)
+
+	subscription, err := scanWebhook(h.db.QueryRowContext(c.Request.Context(), query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			middleware.RespondError(c, apperror.ErrWebhookNotFound)
+			return
+		}
+		middleware.RespondServerError(c, "Failed to update webhook", err)
+		return
+	}
+
+	response := subscription.ToResponse()
+	if req.Secret != "" {
+		response.Secret = subscription.Secret
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Webhook updated successfully",
+		response,
+	))
+}
+
+// DeleteWebhook removes a subscription together with its delivery log
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.db.ExecContext(c.Request.Context(), "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		middleware.RespondServerError(c, "Failed to delete webhook", err)
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	if affected == 0 {
+		middleware.RespondError(c, apperror.ErrWebhookNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Webhook deleted successfully",
+		nil,
+	))
+}
+
+// GetDeliveries lists the deliveries of a subscription, newest first
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var filter models.WebhookDeliveryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		middleware.RespondError(c, apperror.InvalidRequest(err))
+		return
+	}
+
+	// Set default values
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	if !h.webhookExists(c, id) {
+		return
+	}
+
+	where := " WHERE d.subscription_id = $1"
+	args := []interface{}{id}
+	if filter.Status != "" {
+		where += " AND d.status = $2"
+		args = append(args, filter.Status)
+	}
+
+	// Get total count
+	var total int
+	err := h.db.QueryRowContext(c.Request.Context(), "SELECT COUNT(*) FROM webhook_deliveries d"+where, args...).Scan(&total)
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries d JOIN outbox_events e ON e.id = d.event_id" + where +
+		fmt.Sprintf(" ORDER BY d.id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDeliveryResponse{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			middleware.RespondServerError(c, "Database error", err)
+			return
+		}
+		deliveries = append(deliveries, delivery.ToResponse())
+	}
+
+	if err = rows.Err(); err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	response := models.WebhookDeliveryListResponse{
+		Deliveries: deliveries,
+		Total:      total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Deliveries retrieved successfully",
+		response,
+	))
+}
+
+// GetDelivery retrieves a delivery with the log of its attempts
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	id, deliveryID, ok := parseDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	query := "SELECT " + deliveryColumns + ` FROM webhook_deliveries d JOIN outbox_events e ON e.id = d.event_id
+			  WHERE d.id = $1 AND d.subscription_id = $2`
+
+	delivery, err := scanDelivery(h.db.QueryRowContext(c.Request.Context(), query, deliveryID, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			middleware.RespondError(c, apperror.ErrDeliveryNotFound)
+			return
+		}
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	rows, err := h.db.QueryContext(c.Request.Context(), `SELECT attempt, status_code, error, response_body, duration_ms, created_at
+		FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY id`, deliveryID)
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+	defer rows.Close()
+
+	response := delivery.ToResponse()
+	response.Log = []models.WebhookDeliveryAttemptResponse{}
+	for rows.Next() {
+		var attempt models.WebhookDeliveryAttempt
+		err := rows.Scan(
+			&attempt.Attempt,
+			&attempt.StatusCode,
+			&attempt.Error,
+			&attempt.ResponseBody,
+			&attempt.DurationMS,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			middleware.RespondServerError(c, "Database error", err)
+			return
+		}
+		response.Log = append(response.Log, attempt.ToResponse())
+	}
+
+	if err = rows.Err(); err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Delivery retrieved successfully",
+		response,
+	))
+}
+
+// RetryDelivery gives a dead delivery a fresh set of attempts, starting now
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	id, deliveryID, ok := parseDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	query := `WITH retried AS (
+				  UPDATE webhook_deliveries SET status = $3, attempts = 0, next_attempt_at = now(), updated_at = now()
+				  WHERE id = $1 AND subscription_id = $2 AND status = $4
+				  RETURNING *
+			  )
+			  SELECT ` + deliveryColumns + ` FROM retried d JOIN outbox_events e ON e.id = d.event_id`
+
+	delivery, err := scanDelivery(h.db.QueryRowContext(c.Request.Context(), query,
+		deliveryID, id, models.DeliveryPending, models.DeliveryDead))
+	if err == sql.ErrNoRows {
+		// Tell a missing delivery apart from one that is not dead
+		var exists bool
+		err = h.db.QueryRowContext(c.Request.Context(), "SELECT EXISTS(SELECT 1 FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2)",
+			deliveryID, id).Scan(&exists)
+		if err == nil && exists {
+			middleware.RespondError(c, apperror.ErrDeliveryNotDead)
+			return
+		}
+		if err == nil {
+			middleware.RespondError(c, apperror.ErrDeliveryNotFound)
+			return
+		}
+	}
+	if err != nil {
+		middleware.RespondServerError(c, "Failed to retry delivery", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		"Delivery scheduled for retry",
+		delivery.ToResponse(),
+	))
+}
+
+// checkURL only accepts absolute http(s) URLs, and only https when
+// required, since the payload carries employee data
+func (h *WebhookHandler) checkURL(raw string) *apperror.Error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return apperror.ErrInvalidWebhookURL
+	}
+	if h.requireHTTPS && u.Scheme != "https" {
+		return apperror.ErrInvalidWebhookURL.WithDetail("Webhook URL must use https")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return apperror.ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// webhookExists writes a 404 response and returns false when there is no
+// subscription with id
+func (h *WebhookHandler) webhookExists(c *gin.Context, id int) bool {
+	var exists bool
+	err := h.db.QueryRowContext(c.Request.Context(), "SELECT EXISTS(SELECT 1 FROM webhook_subscriptions WHERE id = $1)", id).Scan(&exists)
+	if err != nil {
+		middleware.RespondServerError(c, "Database error", err)
+		return false
+	}
+	if !exists {
+		middleware.RespondError(c, apperror.ErrWebhookNotFound)
+		return false
+	}
+	return true
+}
+
+// scanWebhook reads webhookColumns from row
+func scanWebhook(row interface{ Scan(...any) error }) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.URL,
+		&subscription.Secret,
+		pq.Array(&subscription.EventTypes),
+		&subscription.Description,
+		&subscription.IsActive,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	return subscription, err
+}
+
+// scanDelivery reads deliveryColumns from row
+func scanDelivery(row interface{ Scan(...any) error }) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.SubscriptionID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	)
+	return delivery, err
+}
+
+// parseWebhookID reads the :id path parameter, writing a 400 response when it is invalid
+func parseWebhookID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middleware.RespondError(c, apperror.ErrInvalidWebhookID)
+		return 0, false
+	}
+	return id, true
+}
+
+// parseDeliveryID reads the :id and :deliveryId path parameters
+func parseDeliveryID(c *gin.Context) (int, int64, bool) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return 0, 0, false
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		middleware.RespondError(c, apperror.ErrInvalidDeliveryID)
+		return 0, 0, false
+	}
+	return id, deliveryID, true
+}
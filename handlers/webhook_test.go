@@ -0,0 +1,29 @@
+package handlers
+
+import "testing"
+
+func TestCheckWebhookURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		requireHTTPS bool
+		ok           bool
+	}{
+		{"https://hooks.example.com/employees", true, true},
+		{"https://hooks.example.com/employees", false, true},
+		{"http://hooks.example.com/employees", false, true},
+		{"http://hooks.example.com/employees", true, false},
+		{"ftp://hooks.example.com/employees", false, false},
+		{"javascript:alert(1)", false, false},
+		{"/employees", false, false},
+		{"hooks.example.com/employees", false, false},
+		{"https://", false, false},
+		{"https://hooks.example.com/%zz", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		h := NewWebhookHandler(nil, tt.requireHTTPS)
+		if err := h.checkURL(tt.url); (err == nil) != tt.ok {
+			t.Errorf("checkURL(%q) with requireHTTPS=%t = %v, want ok %t", tt.url, tt.requireHTTPS, err, tt.ok)
+		}
+	}
+}
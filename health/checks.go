@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+)
+
+// DatabasePing checks that Postgres answers within the check timeout
+func DatabasePing(db *sql.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) (Status, string) {
+		if err := db.PingContext(ctx); err != nil {
+			return StatusDown, err.Error()
+		}
+		return StatusUp, ""
+	})
+}
+
+// Migrations checks that the schema is at least at the version this build
+// expects. A newer schema is fine: it means a newer replica migrated first.
+func Migrations(db *sql.DB, expected int) Checker {
+	return CheckerFunc(func(ctx context.Context) (Status, string) {
+		var version int
+		err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+		if err != nil {
+			return StatusDown, err.Error()
+		}
+		message := fmt.Sprintf("schema version %d, expected %d", version, expected)
+		if version < expected {
+			return StatusDown, message
+		}
+		return StatusUp, message
+	})
+}
+
+// PoolSaturation reports degraded when the share of open connections in use
+// reaches degradedAt or callers had to wait for a connection since the last
+// check. A busy pool never fails readiness, since pulling the instance out of
+// rotation would only shift the load onto the remaining replicas.
+func PoolSaturation(db *sql.DB, degradedAt float64) Checker {
+	var lastWaitCount atomic.Int64
+	return CheckerFunc(func(ctx context.Context) (Status, string) {
+		stats := db.Stats()
+		if stats.MaxOpenConnections == 0 {
+			return StatusUp, ""
+		}
+
+		newWaits := stats.WaitCount - lastWaitCount.Swap(stats.WaitCount)
+		usage := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		message := fmt.Sprintf("%d/%d connections in use, %d new waits", stats.InUse, stats.MaxOpenConnections, newWaits)
+
+		if usage >= degradedAt || newWaits > 0 {
+			return StatusDegraded, message
+		}
+		return StatusUp, message
+	})
+}
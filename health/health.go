@@ -0,0 +1,148 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the state of a single component or of the service as a whole
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// severity orders statuses so the worst component decides the overall status
+var severity = map[Status]int{
+	StatusUp:       0,
+	StatusDegraded: 1,
+	StatusDown:     2,
+}
+
+// Checker probes one dependency. The message explains non-up results.
+type Checker interface {
+	Check(ctx context.Context) (Status, string)
+}
+
+// CheckerFunc adapts a function to the Checker interface
+type CheckerFunc func(ctx context.Context) (Status, string)
+
+// Check implements Checker
+func (f CheckerFunc) Check(ctx context.Context) (Status, string) {
+	return f(ctx)
+}
+
+// ComponentResult is the outcome of one check
+type ComponentResult struct {
+	Status    Status  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Message   string  `json:"message,omitempty"`
+}
+
+// Report is the readiness breakdown returned to probes
+type Report struct {
+	Status     Status                     `json:"status"`
+	Components map[string]ComponentResult `json:"components"`
+}
+
+type registeredCheck struct {
+	name    string
+	checker Checker
+}
+
+// Registry runs the registered checks concurrently, each with a timeout
+type Registry struct {
+	timeout time.Duration
+	ready   atomic.Bool
+
+	mu     sync.RWMutex
+	checks []registeredCheck
+}
+
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout: timeout,
+	}
+}
+
+// Register adds a named check to readiness
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, registeredCheck{name: name, checker: checker})
+}
+
+// SetReady marks whether the server accepts traffic. It is false until
+// startup completes and again once shutdown begins.
+func (r *Registry) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+// Ready reports what SetReady last set
+func (r *Registry) Ready() bool {
+	return r.ready.Load()
+}
+
+// Run executes all checks and aggregates their results
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]registeredCheck(nil), r.checks...)
+	r.mu.RUnlock()
+
+	report := Report{
+		Status:     StatusUp,
+		Components: make(map[string]ComponentResult, len(checks)+1),
+	}
+
+	if !r.ready.Load() {
+		report.Components["server"] = ComponentResult{Status: StatusDown, Message: "not accepting traffic"}
+		report.Status = StatusDown
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check registeredCheck) {
+			defer wg.Done()
+			result := r.runOne(ctx, check.checker)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[check.name] = result
+			if severity[result.Status] > severity[report.Status] {
+				report.Status = result.Status
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (r *Registry) runOne(ctx context.Context, checker Checker) ComponentResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan ComponentResult, 1)
+	go func() {
+		status, message := checker.Check(ctx)
+		done <- ComponentResult{Status: status, Message: message}
+	}()
+
+	// Do not wait on checks that ignore their context
+	var result ComponentResult
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = ComponentResult{Status: StatusDown, Message: "check timed out"}
+	}
+
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	return result
+}
@@ -0,0 +1,156 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"go-crud-employee/database"
+	"go-crud-employee/database/dbtest"
+)
+
+// fixed is a check that always reports status
+func fixed(status Status) Checker {
+	return CheckerFunc(func(context.Context) (Status, string) { return status, "" })
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		ready  bool
+		checks map[string]Status
+		want   Status
+	}{
+		{"no checks", true, nil, StatusUp},
+		{"all up", true, map[string]Status{"database": StatusUp, "migrations": StatusUp}, StatusUp},
+		{"one degraded", true, map[string]Status{"database": StatusUp, "pool": StatusDegraded}, StatusDegraded},
+		{"down wins over degraded", true, map[string]Status{"database": StatusDown, "pool": StatusDegraded}, StatusDown},
+		{"not ready", false, map[string]Status{"database": StatusUp}, StatusDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry(time.Second)
+			registry.SetReady(tt.ready)
+			for name, status := range tt.checks {
+				registry.Register(name, fixed(status))
+			}
+
+			report := registry.Run(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("status = %s, want %s", report.Status, tt.want)
+			}
+			for name, status := range tt.checks {
+				if got := report.Components[name].Status; got != status {
+					t.Errorf("component %s = %s, want %s", name, got, status)
+				}
+			}
+			if _, ok := report.Components["server"]; ok == tt.ready {
+				t.Errorf("server component reported = %v, want %v", ok, !tt.ready)
+			}
+		})
+	}
+}
+
+func TestRunTimesOutChecks(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+
+	registry := NewRegistry(timeout)
+	registry.SetReady(true)
+	// A check that ignores its context must not hold up the report
+	registry.Register("slow", CheckerFunc(func(context.Context) (Status, string) {
+		<-release
+		return StatusUp, ""
+	}))
+	registry.Register("fast", fixed(StatusUp))
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > 5*timeout {
+		t.Errorf("Run took %v, want about %v", elapsed, timeout)
+	}
+
+	if report.Status != StatusDown {
+		t.Errorf("status = %s, want %s", report.Status, StatusDown)
+	}
+	if got := report.Components["slow"]; got.Status != StatusDown || got.Message != "check timed out" {
+		t.Errorf("slow = %+v, want down with \"check timed out\"", got)
+	}
+	if got := report.Components["fast"]; got.Status != StatusUp {
+		t.Errorf("fast = %+v, want up", got)
+	}
+}
+
+func TestPoolSaturation(t *testing.T) {
+	db := dbtest.Open(t)
+	db.SetMaxOpenConns(2)
+	ctx := context.Background()
+	check := PoolSaturation(db.DB, 1)
+
+	if status, message := check.Check(ctx); status != StatusUp {
+		t.Errorf("idle pool = %s (%s), want up", status, message)
+	}
+
+	held := holdConns(t, db, 2)
+	if status, message := check.Check(ctx); status != StatusDegraded {
+		t.Errorf("full pool = %s (%s), want degraded", status, message)
+	}
+
+	// A caller waiting for a connection degrades the next check even after
+	// the pool has drained
+	waited := make(chan error, 1)
+	go func() {
+		_, err := db.ExecContext(ctx, "SELECT 1")
+		waited <- err
+	}()
+	for db.Stats().WaitCount == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	held[0].Close()
+	if err := <-waited; err != nil {
+		t.Fatal(err)
+	}
+	held[1].Close()
+	if status, message := check.Check(ctx); status != StatusDegraded {
+		t.Errorf("after a wait = %s (%s), want degraded", status, message)
+	}
+	if status, message := check.Check(ctx); status != StatusUp {
+		t.Errorf("no new waits = %s (%s), want up", status, message)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	db := dbtest.Open(t)
+	latest := database.LatestMigration()
+
+	tests := []struct {
+		expected int
+		want     Status
+	}{
+		{latest, StatusUp},
+		{latest - 1, StatusUp},
+		{latest + 1, StatusDown},
+	}
+	for _, tt := range tests {
+		if status, message := Migrations(db.DB, tt.expected).Check(context.Background()); status != tt.want {
+			t.Errorf("expected %d: status = %s (%s), want %s", tt.expected, status, message, tt.want)
+		}
+	}
+}
+
+// holdConns checks out n connections until the test ends or they are closed
+func holdConns(t *testing.T, db *database.DB, n int) []*sql.Conn {
+	t.Helper()
+	conns := make([]*sql.Conn, n)
+	for i := range conns {
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conns[i] = conn
+	}
+	return conns
+}
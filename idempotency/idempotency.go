@@ -0,0 +1,128 @@
+// Package idempotency stores the responses of requests sent with an
+// Idempotency-Key header, so a client retrying after a timeout gets the
+// original response instead of running the request twice.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-crud-employee/database"
+)
+
+// beginQuery claims a key. An existing row is only taken over once it has
+// expired, or when its request has been in progress longer than the lock
+// timeout, which means the replica handling it died.
+const beginQuery = `
+	INSERT INTO idempotency_keys AS k (scope, key, fingerprint, created_at, expires_at)
+	VALUES ($1, $2, $3, now(), now() + $4::double precision * interval '1 second')
+	ON CONFLICT (scope, key) DO UPDATE SET
+		fingerprint = EXCLUDED.fingerprint,
+		status_code = NULL,
+		content_type = NULL,
+		response_body = NULL,
+		created_at = EXCLUDED.created_at,
+		expires_at = EXCLUDED.expires_at
+	WHERE k.expires_at < now()
+		OR (k.status_code IS NULL AND k.created_at < now() - $5::double precision * interval '1 second')
+	RETURNING true`
+
+// Record is what an earlier request with the same key left behind
+type Record struct {
+	Fingerprint string
+	Completed   bool // False while the earlier request is still running
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Store keeps keys in the idempotency_keys table, shared by all replicas
+type Store struct {
+	db          *database.DB
+	ttl         time.Duration
+	lockTimeout time.Duration
+}
+
+// NewStore returns a store that keeps responses for ttl and lets a retry
+// take over a key whose request has not finished within lockTimeout
+func NewStore(db *database.DB, ttl, lockTimeout time.Duration) *Store {
+	return &Store{db: db, ttl: ttl, lockTimeout: lockTimeout}
+}
+
+// Fingerprint identifies a request by method, path and body
+func Fingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", method, path)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Begin claims key within scope for a request with fingerprint. It returns
+// nil when the caller owns the key and must Complete or Release it, and the
+// earlier request's record otherwise.
+func (s *Store) Begin(ctx context.Context, scope, key, fingerprint string) (*Record, error) {
+	// The row can vanish between the two statements when its request fails
+	// and releases it; the second attempt then claims it
+	for range 2 {
+		var claimed bool
+		err := s.db.QueryRowContext(ctx, beginQuery, scope, key, fingerprint, s.ttl.Seconds(), s.lockTimeout.Seconds()).Scan(&claimed)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to claim idempotency key: %v", err)
+		}
+
+		var record Record
+		var statusCode sql.NullInt64
+		var contentType sql.NullString
+		err = s.db.QueryRowContext(ctx, `SELECT fingerprint, status_code, content_type, response_body
+			FROM idempotency_keys WHERE scope = $1 AND key = $2`, scope, key,
+		).Scan(&record.Fingerprint, &statusCode, &contentType, &record.Body)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read idempotency key: %v", err)
+		}
+		record.Completed = statusCode.Valid
+		record.StatusCode = int(statusCode.Int64)
+		record.ContentType = contentType.String
+		return &record, nil
+	}
+	return nil, errors.New("failed to claim idempotency key: key keeps changing")
+}
+
+// Complete stores the response of the request that claimed key
+func (s *Store) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE idempotency_keys SET status_code = $3, content_type = $4, response_body = $5
+		WHERE scope = $1 AND key = $2`, scope, key, statusCode, contentType, body)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %v", err)
+	}
+	return nil
+}
+
+// Release frees key after a failure that a retry might not hit, such as a
+// server error, so the retry runs the request again
+func (s *Store) Release(ctx context.Context, scope, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status_code IS NULL", scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %v", err)
+	}
+	return nil
+}
+
+// Prune deletes expired keys
+func (s *Store) Prune(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < now()")
+	if err != nil {
+		return fmt.Errorf("failed to prune idempotency keys: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-crud-employee/database/dbtest"
+)
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint("POST", "/api/v1/employees/", []byte(`{"nip":"EMP001"}`))
+	if base != Fingerprint("POST", "/api/v1/employees/", []byte(`{"nip":"EMP001"}`)) {
+		t.Error("fingerprint of the same request differs")
+	}
+	for _, other := range []string{
+		Fingerprint("PUT", "/api/v1/employees/", []byte(`{"nip":"EMP001"}`)),
+		Fingerprint("POST", "/api/v1/users/", []byte(`{"nip":"EMP001"}`)),
+		Fingerprint("POST", "/api/v1/employees/", []byte(`{"nip":"EMP002"}`)),
+	} {
+		if other == base {
+			t.Error("fingerprint ignores part of the request")
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "idempotency_keys")
+	ctx := context.Background()
+	store := NewStore(db, time.Hour, time.Minute)
+
+	// The first request claims the key; a concurrent one sees it in progress
+	if record, err := store.Begin(ctx, "user:1", "key-1", "fp"); err != nil || record != nil {
+		t.Fatalf("Begin = %+v, %v; want the key claimed", record, err)
+	}
+	record, err := store.Begin(ctx, "user:1", "key-1", "fp")
+	if err != nil || record == nil || record.Completed || record.Fingerprint != "fp" {
+		t.Fatalf("Begin while in progress = %+v, %v", record, err)
+	}
+
+	// Other clients have their own keys
+	if record, err := store.Begin(ctx, "user:2", "key-1", "fp"); err != nil || record != nil {
+		t.Fatalf("Begin for another client = %+v, %v; want the key claimed", record, err)
+	}
+
+	if err := store.Complete(ctx, "user:1", "key-1", 201, "application/json", []byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	record, err = store.Begin(ctx, "user:1", "key-1", "fp")
+	if err != nil || record == nil || !record.Completed || record.StatusCode != 201 || string(record.Body) != `{"id":1}` {
+		t.Fatalf("Begin after Complete = %+v, %v", record, err)
+	}
+
+	// A completed key is not released; an unfinished one is
+	if err := store.Release(ctx, "user:1", "key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if record, _ := store.Begin(ctx, "user:1", "key-1", "fp"); record == nil || !record.Completed {
+		t.Fatal("Release dropped a completed key")
+	}
+	if err := store.Release(ctx, "user:2", "key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if record, _ := store.Begin(ctx, "user:2", "key-1", "fp"); record != nil {
+		t.Fatal("released key was not claimable again")
+	}
+
+	// Expired keys are taken over and pruned
+	if _, err := db.ExecContext(ctx, "UPDATE idempotency_keys SET expires_at = now() - interval '1 second' WHERE scope = 'user:1'"); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := store.Begin(ctx, "user:1", "key-1", "fp2"); err != nil || record != nil {
+		t.Fatalf("Begin on an expired key = %+v, %v; want the key claimed", record, err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE idempotency_keys SET expires_at = now() - interval '1 second'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Prune(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var left int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM idempotency_keys").Scan(&left); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d keys left after Prune, want 0", left)
+	}
+}
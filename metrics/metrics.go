@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "employee_api"
+
+// Registry holds every metric exported by the API
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts requests by route template, method and status code
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests processed, by route template, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes request latency by route template and method
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// HTTPInFlight tracks requests currently being served
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	// EmployeesCreated counts employees created through the API
+	EmployeesCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "employees_created_total",
+		Help:      "Employees created.",
+	})
+
+	// EmployeesDeactivated counts employees deactivated through the API
+	EmployeesDeactivated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "employees_deactivated_total",
+		Help:      "Employees deactivated.",
+	})
+
+	// Logins counts login attempts by method and result
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts, by method (password, oidc) and result (success, failure).",
+	}, []string{"method", "result"})
+
+	// RateLimited counts requests rejected by the rate limiter by route group
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected by the rate limiter, by route group.",
+	}, []string{"group"})
+
+	// WebhookDeliveries counts webhook delivery attempts by result
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts, by result (succeeded, failed, dead).",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		HTTPInFlight,
+		EmployeesCreated,
+		EmployeesDeactivated,
+		Logins,
+		RateLimited,
+		WebhookDeliveries,
+	)
+}
+
+// RegisterDB exports the connection pool statistics (sql.DBStats) of db
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// LoginSucceeded records a successful login
+func LoginSucceeded(method string) {
+	Logins.WithLabelValues(method, "success").Inc()
+}
+
+// LoginFailed records a rejected login
+func LoginFailed(method string) {
+	Logins.WithLabelValues(method, "failure").Inc()
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -1,82 +1,221 @@
 package middleware
 
 import (
+	"context"
+	"database/sql"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
+	"go-crud-employee/apperror"
+	"go-crud-employee/database"
 	"go-crud-employee/models"
+	"go-crud-employee/tlsutil"
 	"go-crud-employee/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthMiddleware struct {
-	jwtManager *utils.JWTManager
+	db             *database.DB
+	jwtManager     *utils.JWTManager
+	revocations    *utils.RevocationList
+	clientAccounts map[string]string // Client certificate CN -> service account username
 }
 
-func NewAuthMiddleware(jwtManager *utils.JWTManager) *AuthMiddleware {
+func NewAuthMiddleware(db *database.DB, jwtManager *utils.JWTManager, revocations *utils.RevocationList, clientAccounts map[string]string) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtManager: jwtManager,
+		db:             db,
+		jwtManager:     jwtManager,
+		revocations:    revocations,
+		clientAccounts: clientAccounts,
 	}
 }
 
 // RequireAuth middleware validates JWT token from Authorization header
 func (a *AuthMiddleware) RequireAuth() gin.HandlerFunc {
+	return a.authenticate(false)
+}
+
+// RequireAuthAllowPasswordReset behaves like RequireAuth but also admits users
+// whose password reset is still pending, so they can change their password
+func (a *AuthMiddleware) RequireAuthAllowPasswordReset() gin.HandlerFunc {
+	return a.authenticate(true)
+}
+
+// RequireRole middleware only admits users with one of the given roles.
+// It must run after RequireAuth.
+func (a *AuthMiddleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetUserFromContext(c)
+		if !ok {
+			RespondError(c, apperror.ErrUnauthenticated)
+			return
+		}
+
+		for _, role := range roles {
+			if claims.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		RespondError(c, apperror.ErrForbidden)
+	}
+}
+
+func (a *AuthMiddleware) authenticate(allowPasswordReset bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
-				"Authorization required",
-				"Missing Authorization header",
-			))
-			c.Abort()
+			// Service accounts may present a client certificate instead
+			if username, ok := a.clientAccounts[tlsutil.ClientCommonName(c.Request)]; ok {
+				a.authenticateServiceAccount(c, username)
+				return
+			}
+			RespondError(c, apperror.New(http.StatusUnauthorized, apperror.CodeUnauthenticated, "Authorization required", "Missing Authorization header"))
 			return
 		}
 
 		// Check if header starts with "Bearer "
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
-				"Invalid authorization format",
-				"Authorization header must start with 'Bearer '",
-			))
-			c.Abort()
+			RespondError(c, apperror.New(http.StatusUnauthorized, apperror.CodeUnauthenticated, "Invalid authorization format", "Authorization header must start with 'Bearer '"))
 			return
 		}
 
 		// Extract token
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 		if token == "" {
-			c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
-				"Token required",
-				"Empty token provided",
-			))
-			c.Abort()
+			RespondError(c, apperror.New(http.StatusUnauthorized, apperror.CodeUnauthenticated, "Token required", "Empty token provided"))
 			return
 		}
 
 		// Validate token
 		claims, err := a.jwtManager.ValidateToken(token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
-				"Invalid token",
-				err.Error(),
-			))
-			c.Abort()
+			RespondError(c, apperror.ErrInvalidToken.WithCause(err))
+			return
+		}
+
+		// Reject tokens whose session was signed out remotely
+		if a.revocations.IsRevoked(claims.SessionID) {
+			RespondError(c, apperror.ErrInvalidToken.WithDetail("Session has been revoked"))
+			return
+		}
+
+		// Reload account state so disabled users and role changes take
+		// effect immediately instead of when the token expires
+		var isActive, passwordResetRequired bool
+		err = a.db.QueryRowContext(c.Request.Context(),
+			"SELECT role, is_active, password_reset_required FROM users WHERE id = $1",
+			claims.UserID,
+		).Scan(&claims.Role, &isActive, &passwordResetRequired)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				RespondError(c, apperror.ErrInvalidToken.WithDetail("User no longer exists"))
+				return
+			}
+			RespondServerError(c, "Database error", err)
+			return
+		}
+
+		if !isActive {
+			RespondError(c, apperror.ErrAccountDisabled)
+			return
+		}
+
+		if passwordResetRequired && !allowPasswordReset {
+			RespondError(c, apperror.ErrPasswordResetRequired)
 			return
 		}
 
+		// Track session activity, at most once per minute to limit writes
+		_, err = a.db.ExecContext(c.Request.Context(), `UPDATE user_sessions SET last_seen_at = $1
+			WHERE id = $2 AND last_seen_at < $3`,
+			time.Now(), claims.SessionID, time.Now().Add(-time.Minute))
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "Failed to update session last seen", "error", err)
+		}
+
 		// Store user information in context
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("email", claims.Email)
-		c.Set("claims", claims)
+		setClaims(c, claims)
 
 		// Continue to next handler
 		c.Next()
 	}
 }
 
+// authenticateServiceAccount admits a verified client certificate mapped to
+// a local account. Like tokens, the account is reloaded on every request.
+func (a *AuthMiddleware) authenticateServiceAccount(c *gin.Context, username string) {
+	claims := &models.JWTClaims{Username: username}
+	var isActive bool
+	err := a.db.QueryRowContext(c.Request.Context(),
+		"SELECT id, email, role, is_active FROM users WHERE username = $1",
+		username,
+	).Scan(&claims.UserID, &claims.Email, &claims.Role, &isActive)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			RespondError(c, apperror.New(http.StatusUnauthorized, apperror.CodeUnauthenticated, "Unknown service account", "The client certificate is mapped to an account that does not exist"))
+			return
+		}
+		RespondServerError(c, "Database error", err)
+		return
+	}
+
+	if !isActive {
+		RespondError(c, apperror.ErrAccountDisabled)
+		return
+	}
+
+	setClaims(c, claims)
+	c.Next()
+}
+
+// Recheck tells whether an authenticated caller may still be served. Long
+// running responses such as event streams call it periodically, since the
+// checks in RequireAuth only happen once per request.
+func (a *AuthMiddleware) Recheck(ctx context.Context, claims *models.JWTClaims) error {
+	// Service accounts have no token, so nothing expires or is revoked
+	if claims.SessionID != "" {
+		if time.Now().Unix() >= claims.Exp {
+			return apperror.ErrInvalidToken.WithDetail("Token has expired")
+		}
+		if a.revocations.IsRevoked(claims.SessionID) {
+			return apperror.ErrInvalidToken.WithDetail("Session has been revoked")
+		}
+	}
+
+	var role string
+	var isActive bool
+	err := a.db.QueryRowContext(ctx, "SELECT role, is_active FROM users WHERE id = $1", claims.UserID).Scan(&role, &isActive)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return apperror.ErrInvalidToken.WithDetail("User no longer exists")
+		}
+		return err
+	}
+	if !isActive {
+		return apperror.ErrAccountDisabled
+	}
+	if role != claims.Role {
+		return apperror.ErrForbidden.WithDetail("Role has changed")
+	}
+	return nil
+}
+
+// setClaims stores the authenticated user in the gin context
+func setClaims(c *gin.Context, claims *models.JWTClaims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("session_id", claims.SessionID)
+	c.Set("claims", claims)
+}
+
 // GetUserFromContext extracts user information from gin context
 func GetUserFromContext(c *gin.Context) (*models.JWTClaims, bool) {
 	claims, exists := c.Get("claims")
@@ -105,4 +244,4 @@ func GetUserID(c *gin.Context) (int, bool) {
 	}
 
 	return id, true
-}
\ No newline at end of file
+}
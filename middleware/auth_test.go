@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/config"
+	"go-crud-employee/database/dbtest"
+	"go-crud-employee/models"
+	"go-crud-employee/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthenticateDisabledAccount(t *testing.T) {
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "users")
+	gin.SetMode(gin.TestMode)
+
+	user := &models.User{Username: "rina", Email: "rina@example.com", Role: models.RoleUser}
+	err := db.QueryRowContext(context.Background(), `INSERT INTO users (username, email, password_hash, is_active)
+		VALUES ($1, $2, 'x', false) RETURNING id`, user.Username, user.Email).Scan(&user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret-with-enough-length-for-hs256"
+	cfg.JWT.Expiry = time.Hour
+	jwtManager := utils.NewJWTManager(cfg)
+	token, _, err := jwtManager.GenerateToken(user, "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := NewAuthMiddleware(db, jwtManager, utils.NewRevocationList(), map[string]string{"erp-sync": "rina"})
+	router := gin.New()
+	router.GET("/", auth.RequireAuth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	bearer := httptest.NewRequest(http.MethodGet, "/", nil)
+	bearer.Header.Set("Authorization", "Bearer "+token)
+	certificate := httptest.NewRequest(http.MethodGet, "/", nil)
+	certificate.TLS = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "erp-sync"}}}},
+	}
+
+	// A disabled account is refused the same way on every path, and as
+	// Recheck refuses it on open streams
+	for name, req := range map[string]*http.Request{"token": bearer, "client certificate": certificate} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var body struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != apperror.ErrAccountDisabled.Status || body.Code != string(apperror.CodeAccountDisabled) {
+			t.Errorf("%s: status = %d, code %q; want %d %s", name, w.Code, body.Code, apperror.ErrAccountDisabled.Status, apperror.CodeAccountDisabled)
+		}
+	}
+
+	claims := &models.JWTClaims{UserID: user.ID, Role: user.Role}
+	if err := auth.Recheck(context.Background(), claims); err != apperror.ErrAccountDisabled {
+		t.Errorf("Recheck = %v, want %v", err, apperror.ErrAccountDisabled)
+	}
+}
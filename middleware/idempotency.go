@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/idempotency"
+
+	"github.com/gin-gonic/gin"
+)
+
+const idempotencyHeader = "Idempotency-Key"
+
+// IdempotencyStore claims keys and keeps the responses of their requests;
+// *idempotency.Store is the implementation shared by all replicas
+type IdempotencyStore interface {
+	Begin(ctx context.Context, scope, key, fingerprint string) (*idempotency.Record, error)
+	Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, scope, key string) error
+}
+
+// Idempotency makes POST requests with an Idempotency-Key header safe to
+// retry: a repeat gets the stored response, marked with Idempotent-Replayed,
+// instead of running again. Keys are scoped per client, so it must run after
+// the group's authentication middleware.
+func Idempotency(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(key) {
+			RespondError(c, apperror.ErrInvalidIdempotencyKey)
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			RespondError(c, apperror.InvalidRequest(err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		scope := clientKey(c)
+		fingerprint := idempotency.Fingerprint(c.Request.Method, c.Request.URL.Path, body)
+		record, err := store.Begin(ctx, scope, key, fingerprint)
+		if err != nil {
+			// Running the request anyway could create the duplicate the
+			// client is guarding against
+			RespondServerError(c, "Failed to check idempotency key", err)
+			return
+		}
+
+		if record != nil {
+			switch {
+			case record.Fingerprint != fingerprint:
+				RespondError(c, apperror.ErrIdempotencyKeyReused)
+			case !record.Completed:
+				c.Header("Retry-After", "1")
+				RespondError(c, apperror.ErrIdempotencyKeyInProgress)
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.StatusCode, record.ContentType, record.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// The response is stored even if the client has gone, since that is
+		// when it will retry
+		ctx = context.WithoutCancel(ctx)
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError || status == apperror.StatusClientClosedRequest {
+			// The outcome may differ on retry, so the retry runs again
+			err = store.Release(ctx, scope, key)
+		} else {
+			err = store.Complete(ctx, scope, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to record idempotent request", "error", err)
+		}
+	}
+}
+
+// validIdempotencyKey admits keys such as UUIDs that are safe to store
+func validIdempotencyKey(key string) bool {
+	if len(key) > 255 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// responseRecorder keeps a copy of the response body while writing it
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go-crud-employee/idempotency"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyStore follows the semantics of idempotency.Store in memory
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*idempotency.Record
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string]*idempotency.Record)}
+}
+
+func (s *memoryIdempotencyStore) Begin(ctx context.Context, scope, key, fingerprint string) (*idempotency.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[scope+" "+key]; ok {
+		copied := *record
+		return &copied, nil
+	}
+	s.records[scope+" "+key] = &idempotency.Record{Fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[scope+" "+key]
+	record.Completed, record.StatusCode, record.ContentType, record.Body = true, statusCode, contentType, body
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record := s.records[scope+" "+key]; record != nil && !record.Completed {
+		delete(s.records, scope+" "+key)
+	}
+	return nil
+}
+
+// idempotencyRouter serves POST /items behind the middleware; respond
+// decides each response and calls counts how often the handler ran
+func idempotencyRouter(store IdempotencyStore, respond func(c *gin.Context)) (*gin.Engine, *atomic.Int32) {
+	gin.SetMode(gin.TestMode)
+	var calls atomic.Int32
+	router := gin.New()
+	router.POST("/items", func(c *gin.Context) {
+		c.Set("api_key", c.GetHeader("X-Client"))
+	}, Idempotency(store), func(c *gin.Context) {
+		calls.Add(1)
+		respond(c)
+	})
+	return router, &calls
+}
+
+func postItem(router http.Handler, key, client, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Client", client)
+	if key != "" {
+		req.Header.Set(idempotencyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	router, calls := idempotencyRouter(newMemoryIdempotencyStore(), func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": 42})
+	})
+
+	first := postItem(router, "key-1", "a", `{"name":"Rina"}`)
+	second := postItem(router, "key-1", "a", `{"name":"Rina"}`)
+
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls.Load())
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("only the replay should carry Idempotent-Replayed")
+	}
+	if second.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Errorf("replayed Content-Type = %q, want %q", second.Header().Get("Content-Type"), first.Header().Get("Content-Type"))
+	}
+
+	// Keys are scoped per client, and requests without a key always run
+	postItem(router, "key-1", "b", `{"name":"Rina"}`)
+	postItem(router, "", "a", `{"name":"Rina"}`)
+	postItem(router, "", "a", `{"name":"Rina"}`)
+	if calls.Load() != 4 {
+		t.Errorf("handler ran %d times, want 4", calls.Load())
+	}
+}
+
+func TestIdempotencyFingerprintMismatch(t *testing.T) {
+	router, calls := idempotencyRouter(newMemoryIdempotencyStore(), func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": 42})
+	})
+
+	postItem(router, "key-1", "a", `{"name":"Rina"}`)
+	w := postItem(router, "key-1", "a", `{"name":"Budi"}`)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+	router, calls := idempotencyRouter(newMemoryIdempotencyStore(), func(c *gin.Context) {
+		close(started)
+		<-finish
+		c.JSON(http.StatusCreated, gin.H{"id": 42})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postItem(router, "key-1", "a", `{"name":"Rina"}`) }()
+	<-started
+
+	w := postItem(router, "key-1", "a", `{"name":"Rina"}`)
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("concurrent request = %d with Retry-After %q, want %d with Retry-After", w.Code, w.Header().Get("Retry-After"), http.StatusConflict)
+	}
+
+	close(finish)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Errorf("first request = %d, want %d", first.Code, http.StatusCreated)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyReleasesAfterServerError(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	router, calls := idempotencyRouter(newMemoryIdempotencyStore(), func(c *gin.Context) {
+		if failing.Load() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database down"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": 42})
+	})
+
+	if w := postItem(router, "key-1", "a", `{"name":"Rina"}`); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first request = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+
+	// The retry runs again instead of replaying the failure
+	failing.Store(false)
+	if w := postItem(router, "key-1", "a", `{"name":"Rina"}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("retry = %d, replayed %q; want a fresh %d", w.Code, w.Header().Get("Idempotent-Replayed"), http.StatusCreated)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2", calls.Load())
+	}
+
+	// Client errors are final and replayed
+	router, calls = idempotencyRouter(newMemoryIdempotencyStore(), func(c *gin.Context) {
+		c.JSON(http.StatusConflict, gin.H{"error": "duplicate"})
+	})
+	postItem(router, "key-1", "a", `{"name":"Rina"}`)
+	if w := postItem(router, "key-1", "a", `{"name":"Rina"}`); w.Code != http.StatusConflict || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry of a 409 = %d, replayed %q; want the stored 409", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyInvalidKey(t *testing.T) {
+	router, calls := idempotencyRouter(newMemoryIdempotencyStore(), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	for _, key := range []string{"has space", "tab\tkey", strings.Repeat("k", 256), "ключ"} {
+		if w := postItem(router, key, "a", `{}`); w.Code != http.StatusBadRequest {
+			t.Errorf("key %q: status = %d, want %d", key, w.Code, http.StatusBadRequest)
+		}
+	}
+	if calls.Load() != 0 {
+		t.Errorf("handler ran %d times, want 0", calls.Load())
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-crud-employee/apperror"
+	"go-crud-employee/database"
+	"go-crud-employee/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// problemJSON is the RFC 7807 media type
+const problemJSON = "application/problem+json"
+
+// problemDetails makes every error response RFC 7807; it is set once at startup
+var problemDetails bool
+
+// UseProblemDetails switches all error responses to application/problem+json.
+// Otherwise only clients that ask for it in Accept receive that format.
+func UseProblemDetails(enabled bool) {
+	problemDetails = enabled
+}
+
+// RespondError writes err as an error response and aborts the chain. Errors
+// that are not *apperror.Error are reported as internal errors; their cause
+// is logged, never returned.
+func RespondError(c *gin.Context, err error) {
+	respond(c, classify(c, err, "Internal server error"))
+}
+
+// RespondServerError is RespondError for unexpected failures, with message
+// naming the operation that failed
+func RespondServerError(c *gin.Context, message string, err error) {
+	respond(c, classify(c, err, message))
+}
+
+// classify maps err onto the error taxonomy. Abandoned requests and timed
+// out statements are not server faults and get 499 and 504.
+func classify(c *gin.Context, err error, message string) *apperror.Error {
+	var appErr *apperror.Error
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case database.IsCanceled(c.Request.Context(), err):
+		return apperror.ErrClientClosedRequest.WithCause(err)
+	case database.IsTimeout(err):
+		return apperror.ErrTimeout.WithCause(err)
+	}
+	if pgErr := apperror.FromPostgres(err); pgErr != nil {
+		return pgErr
+	}
+	return apperror.Internal(message, err)
+}
+
+func respond(c *gin.Context, appErr *apperror.Error) {
+	ctx := c.Request.Context()
+	switch {
+	case appErr.Status >= http.StatusInternalServerError:
+		slog.ErrorContext(ctx, "Request failed", "code", appErr.Code, "error", appErr.Cause)
+	case appErr.Cause != nil:
+		slog.DebugContext(ctx, "Request rejected", "code", appErr.Code, "error", appErr.Cause)
+	}
+
+	requestID := GetRequestID(c)
+	if problemDetails || strings.Contains(c.GetHeader("Accept"), problemJSON) {
+		c.Header("Content-Type", problemJSON)
+		c.JSON(appErr.Status, models.ProblemDetails{
+			Type:      "about:blank",
+			Title:     appErr.Message,
+			Status:    appErr.Status,
+			Detail:    appErr.Detail,
+			Instance:  c.Request.URL.Path,
+			Code:      string(appErr.Code),
+			Errors:    appErr.Fields,
+			RequestID: requestID,
+		})
+		c.Abort()
+		return
+	}
+
+	response := models.NewErrorResponse(appErr.Message, appErr.Detail)
+	response.Code = string(appErr.Code)
+	response.Details = appErr.Fields
+	response.RequestID = requestID
+	c.JSON(appErr.Status, response)
+	c.Abort()
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"go-crud-employee/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SCIMAuth middleware admits the provisioning client by its static bearer token
+func SCIMAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || provided == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Header("Content-Type", "application/scim+json; charset=utf-8")
+			c.JSON(http.StatusUnauthorized, models.SCIMError{
+				Schemas: []string{models.SCIMSchemaError},
+				Status:  "401",
+				Detail:  "Invalid or missing bearer token",
+			})
+			c.Abort()
+			return
+		}
+
+		// Identifies the client to the rate limiter
+		c.Set("api_key", "scim")
+
+		c.Next()
+	}
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
@@ -72,6 +73,55 @@ type EmployeeListResponse struct {
 	Offset    int                `json:"offset"`
 }
 
+// Batch operations and modes
+const (
+	BatchCreate     = "create"
+	BatchUpdate     = "update"
+	BatchDeactivate = "deactivate"
+
+	BatchAtomic     = "atomic"      // Apply every operation or none
+	BatchBestEffort = "best_effort" // Apply the operations that succeed
+)
+
+type BatchEmployeeRequest struct {
+	Mode       string           `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1"`
+}
+
+// BatchOperation is one change in a batch. Update and deactivate target an
+// employee by ID or NIP; Data holds a CreateEmployeeRequest for create and
+// an UpdateEmployeeRequest for update.
+type BatchOperation struct {
+	Op   string          `json:"op"`
+	ID   int             `json:"id,omitempty"`
+	NIP  string          `json:"nip,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// BatchResult is the outcome of the operation at Index. Status is the HTTP
+// status the operation would have had as a single request.
+type BatchResult struct {
+	Index    int               `json:"index"`
+	Op       string            `json:"op"`
+	Status   int               `json:"status"`
+	Employee *EmployeeResponse `json:"employee,omitempty"`
+	Error    *BatchError       `json:"error,omitempty"`
+}
+
+type BatchError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Detail  string       `json:"detail,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+type BatchEmployeeResponse struct {
+	Mode    string        `json:"mode"`
+	Applied int           `json:"applied"`
+	Failed  int           `json:"failed"`
+	Results []BatchResult `json:"results"`
+}
+
 // ToResponse converts Employee model to EmployeeResponse
 func (e *Employee) ToResponse() EmployeeResponse {
 	phone := ""
@@ -98,4 +148,16 @@ func (e *Employee) ToResponse() EmployeeResponse {
 		CreatedAt:  e.CreatedAt.Format("2006-01-02 15:04:05"),
 		UpdatedAt:  e.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
+}
+
+// VisibleTo returns r as role may read it in the event stream, where only
+// admins see salaries
+func (r EmployeeResponse) VisibleTo(role string) any {
+	if role == RoleAdmin {
+		return r
+	}
+	return struct {
+		EmployeeResponse
+		Salary *float64 `json:"salary,omitempty"` // Hides the embedded field
+	}{EmployeeResponse: r}
 }
\ No newline at end of file
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Session struct {
+	ID         string       `json:"id" db:"id"`
+	UserID     int          `json:"user_id" db:"user_id"`
+	UserAgent  string       `json:"user_agent" db:"user_agent"`
+	IPAddress  string       `json:"ip_address" db:"ip_address"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time    `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time    `json:"expires_at" db:"expires_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at" db:"revoked_at"`
+}
+
+type SessionResponse struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IPAddress  string `json:"ip_address"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	ExpiresAt  string `json:"expires_at"`
+	Current    bool   `json:"current"` // True for the session making the request
+}
+
+// ToResponse converts Session model to SessionResponse
+func (s *Session) ToResponse(currentSessionID string) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt.Format("2006-01-02 15:04:05"),
+		LastSeenAt: s.LastSeenAt.Format("2006-01-02 15:04:05"),
+		ExpiresAt:  s.ExpiresAt.Format("2006-01-02 15:04:05"),
+		Current:    s.ID == currentSessionID,
+	}
+}
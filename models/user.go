@@ -4,13 +4,22 @@ import (
 	"time"
 )
 
+// User roles
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
 type User struct {
-	ID           int       `json:"id" db:"id"`
-	Username     string    `json:"username" db:"username"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"` // Never expose password hash in JSON
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID                    int       `json:"id" db:"id"`
+	Username              string    `json:"username" db:"username"`
+	Email                 string    `json:"email" db:"email"`
+	PasswordHash          string    `json:"-" db:"password_hash"` // Never expose password hash in JSON
+	Role                  string    `json:"role" db:"role"`
+	IsActive              bool      `json:"is_active" db:"is_active"`
+	PasswordResetRequired bool      `json:"password_reset_required" db:"password_reset_required"`
+	CreatedAt             time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type LoginRequest struct {
@@ -24,24 +33,87 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
 type LoginResponse struct {
-	Token     string    `json:"token"`
-	User      UserInfo  `json:"user"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Token                 string    `json:"token"`
+	User                  UserInfo  `json:"user"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	PasswordResetRequired bool      `json:"password_reset_required"`
 }
 
 type UserInfo struct {
 	ID       int    `json:"id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+// Admin user management
+
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+	Role     string `json:"role" binding:"omitempty,oneof=admin user"`
+}
+
+type UpdateUserRequest struct {
+	Email string `json:"email" binding:"omitempty,email"`
+	Role  string `json:"role" binding:"omitempty,oneof=admin user"`
+}
+
+type UserStatusRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	// Optional temporary password; one is generated when empty
+	Password string `json:"password" binding:"omitempty,min=6"`
+}
+
+type ResetPasswordResponse struct {
+	User              UserResponse `json:"user"`
+	TemporaryPassword string       `json:"temporary_password,omitempty"`
+}
+
+type UserFilter struct {
+	Role     string `form:"role"`
+	IsActive *bool  `form:"is_active"`
+	Search   string `form:"search"` // Search by username or email
+	Limit    int    `form:"limit"`
+	Offset   int    `form:"offset"`
+}
+
+type UserResponse struct {
+	ID                    int    `json:"id"`
+	Username              string `json:"username"`
+	Email                 string `json:"email"`
+	Role                  string `json:"role"`
+	IsActive              bool   `json:"is_active"`
+	PasswordResetRequired bool   `json:"password_reset_required"`
+	CreatedAt             string `json:"created_at"`
+	UpdatedAt             string `json:"updated_at"`
+}
+
+type UserListResponse struct {
+	Users  []UserResponse `json:"users"`
+	Total  int            `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
 }
 
 type JWTClaims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Exp      int64  `json:"exp"`
-	Iat      int64  `json:"iat"`
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"jti"`
+	Exp       int64  `json:"exp"`
+	Iat       int64  `json:"iat"`
 }
 
 // ToUserInfo converts User model to UserInfo
@@ -50,5 +122,20 @@ func (u *User) ToUserInfo() UserInfo {
 		ID:       u.ID,
 		Username: u.Username,
 		Email:    u.Email,
+		Role:     u.Role,
 	}
-}
\ No newline at end of file
+}
+
+// ToResponse converts User model to UserResponse
+func (u *User) ToResponse() UserResponse {
+	return UserResponse{
+		ID:                    u.ID,
+		Username:              u.Username,
+		Email:                 u.Email,
+		Role:                  u.Role,
+		IsActive:              u.IsActive,
+		PasswordResetRequired: u.PasswordResetRequired,
+		CreatedAt:             u.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:             u.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
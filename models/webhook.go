@@ -0,0 +1,164 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Webhook event types
+const (
+	EventEmployeeCreated     = "employee.created"
+	EventEmployeeUpdated     = "employee.updated"
+	EventEmployeeDeactivated = "employee.deactivated"
+)
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending" // Waiting for its first attempt or a retry
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead" // Gave up after the last retry
+)
+
+type WebhookSubscription struct {
+	ID          int       `json:"id" db:"id"`
+	URL         string    `json:"url" db:"url"`
+	Secret      string    `json:"-" db:"secret"` // Only shown when set
+	EventTypes  []string  `json:"event_types" db:"event_types"`
+	Description string    `json:"description" db:"description"`
+	IsActive    bool      `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url"`
+	EventTypes  []string `json:"event_types" binding:"required,min=1,dive,oneof=employee.created employee.updated employee.deactivated"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=255"` // Generated when empty
+	Description string   `json:"description" binding:"max=255"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         string   `json:"url" binding:"omitempty,url"`
+	EventTypes  []string `json:"event_types" binding:"omitempty,min=1,dive,oneof=employee.created employee.updated employee.deactivated"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=255"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+type WebhookResponse struct {
+	ID          int      `json:"id"`
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret,omitempty"` // Only in create and secret change responses
+	EventTypes  []string `json:"event_types"`
+	Description string   `json:"description"`
+	IsActive    bool     `json:"is_active"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64         `json:"id" db:"id"`
+	SubscriptionID int           `json:"subscription_id" db:"subscription_id"`
+	EventID        int64         `json:"event_id" db:"event_id"`
+	EventType      string        `json:"event_type" db:"event_type"`
+	Status         string        `json:"status" db:"status"`
+	Attempts       int           `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time     `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode sql.NullInt64 `json:"last_status_code" db:"last_status_code"`
+	LastError      string        `json:"last_error" db:"last_error"`
+	DeliveredAt    sql.NullTime  `json:"delivered_at" db:"delivered_at"`
+	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
+}
+
+type WebhookDeliveryAttempt struct {
+	Attempt      int           `json:"attempt" db:"attempt"`
+	StatusCode   sql.NullInt64 `json:"status_code" db:"status_code"`
+	Error        string        `json:"error" db:"error"`
+	ResponseBody string        `json:"response_body" db:"response_body"`
+	DurationMS   int           `json:"duration_ms" db:"duration_ms"`
+	CreatedAt    time.Time     `json:"created_at" db:"created_at"`
+}
+
+type WebhookDeliveryFilter struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             int64                            `json:"id"`
+	SubscriptionID int                              `json:"subscription_id"`
+	EventID        int64                            `json:"event_id"`
+	EventType      string                           `json:"event_type"`
+	Status         string                           `json:"status"`
+	Attempts       int                              `json:"attempts"`
+	NextAttemptAt  string                           `json:"next_attempt_at,omitempty"` // Only while pending
+	LastStatusCode int                              `json:"last_status_code,omitempty"`
+	LastError      string                           `json:"last_error,omitempty"`
+	DeliveredAt    string                           `json:"delivered_at,omitempty"`
+	CreatedAt      string                           `json:"created_at"`
+	Log            []WebhookDeliveryAttemptResponse `json:"log,omitempty"` // Only for a single delivery
+}
+
+type WebhookDeliveryAttemptResponse struct {
+	Attempt      int    `json:"attempt"`
+	StatusCode   int    `json:"status_code,omitempty"`
+	Error        string `json:"error,omitempty"`
+	ResponseBody string `json:"response_body,omitempty"`
+	DurationMS   int    `json:"duration_ms"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Total      int                       `json:"total"`
+	Limit      int                       `json:"limit"`
+	Offset     int                       `json:"offset"`
+}
+
+// ToResponse converts WebhookSubscription model to WebhookResponse
+func (w *WebhookSubscription) ToResponse() WebhookResponse {
+	return WebhookResponse{
+		ID:          w.ID,
+		URL:         w.URL,
+		EventTypes:  w.EventTypes,
+		Description: w.Description,
+		IsActive:    w.IsActive,
+		CreatedAt:   w.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:   w.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// ToResponse converts WebhookDelivery model to WebhookDeliveryResponse
+func (d *WebhookDelivery) ToResponse() WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: int(d.LastStatusCode.Int64),
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if d.Status == DeliveryPending {
+		response.NextAttemptAt = d.NextAttemptAt.Format("2006-01-02 15:04:05")
+	}
+	if d.DeliveredAt.Valid {
+		response.DeliveredAt = d.DeliveredAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return response
+}
+
+// ToResponse converts WebhookDeliveryAttempt model to WebhookDeliveryAttemptResponse
+func (a *WebhookDeliveryAttempt) ToResponse() WebhookDeliveryAttemptResponse {
+	return WebhookDeliveryAttemptResponse{
+		Attempt:      a.Attempt,
+		StatusCode:   int(a.StatusCode.Int64),
+		Error:        a.Error,
+		ResponseBody: a.ResponseBody,
+		DurationMS:   a.DurationMS,
+		CreatedAt:    a.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
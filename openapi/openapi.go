@@ -0,0 +1,328 @@
+// Package openapi builds an OpenAPI 3.1 description of the API from the
+// router's route table and the request and response models, so the
+// published document cannot drift from the routes actually served.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-crud-employee/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Security schemes an operation can require
+const (
+	BearerAuth = "bearerAuth" // JWT from /api/v1/auth/login
+	SCIMAuth   = "scimToken"  // SCIM client token
+)
+
+// Document is the root of an OpenAPI 3.1 document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of one path, keyed by lower-case method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Doc describes a route for the generated document. Bodies and parameters
+// are taken from the model types, so only what reflection cannot know is
+// written by hand.
+type Doc struct {
+	Summary     string
+	Description string
+	Tag         string
+	Security    string // BearerAuth, SCIMAuth or empty for public routes
+	Query       any    // Struct with form tags describing the query string
+	Body        any    // Request body model
+	Response    any    // Value of data in the success envelope; nil for none
+	Status      int    // Success status; 200 when zero
+
+	// Raw responses are not wrapped in the success envelope; ContentType
+	// defaults to application/json
+	Raw         bool
+	ContentType string
+
+	StringParams []string // Path parameters that are not integers
+	SCIM         bool     // SCIM media type and error bodies
+}
+
+// Key returns the key a route is documented under
+func Key(method, path string) string {
+	return method + " " + path
+}
+
+// Generate describes every route in routes that has an entry in docs
+func Generate(info Info, routes gin.RoutesInfo, docs map[string]Doc) *Document {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				BearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				SCIMAuth:   {Type: "http", Scheme: "bearer", Description: "Token from SCIM_TOKEN"},
+			},
+		},
+	}
+
+	// Error bodies are referenced by every operation
+	g.schema(reflect.TypeOf(models.APIResponse{}))
+	g.schema(reflect.TypeOf(models.ProblemDetails{}))
+
+	ids := operationIDs(routes)
+	for _, route := range routes {
+		d, ok := docs[Key(route.Method, route.Path)]
+		if !ok {
+			continue
+		}
+
+		path, params := convertPath(route.Path, d.StringParams)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(route.Method)] = g.operation(ids[Key(route.Method, route.Path)], d, params)
+	}
+	return doc
+}
+
+// Check compares the route table with docs. It returns the routes that are
+// not documented and the documented routes that are not registered.
+func Check(routes gin.RoutesInfo, docs map[string]Doc) (undocumented, unknown []string) {
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		key := Key(route.Method, route.Path)
+		registered[key] = true
+		if _, ok := docs[key]; !ok {
+			undocumented = append(undocumented, key)
+		}
+	}
+	for key := range docs {
+		if !registered[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(undocumented)
+	slices.Sort(unknown)
+	return undocumented, unknown
+}
+
+func (g *generator) operation(id string, d Doc, params []Parameter) *Operation {
+	op := &Operation{
+		OperationID: id,
+		Summary:     d.Summary,
+		Description: d.Description,
+		Parameters:  params,
+		Responses:   make(map[string]Response),
+	}
+	if d.Tag != "" {
+		op.Tags = []string{d.Tag}
+	}
+	if d.Security != "" {
+		op.Security = []map[string][]string{{d.Security: {}}}
+	}
+	if d.Query != nil {
+		op.Parameters = append(op.Parameters, g.queryParameters(reflect.TypeOf(d.Query))...)
+	}
+
+	mediaType := "application/json"
+	if d.SCIM {
+		mediaType = "application/scim+json"
+	}
+	if d.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{mediaType: {Schema: g.schema(reflect.TypeOf(d.Body))}},
+		}
+	}
+
+	status := d.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status)}
+	switch {
+	case d.Raw:
+		contentType := d.ContentType
+		if contentType == "" {
+			contentType = mediaType
+		}
+		if d.Response != nil {
+			success.Content = map[string]MediaType{contentType: {Schema: g.schema(reflect.TypeOf(d.Response))}}
+		} else if d.ContentType != "" {
+			success.Content = map[string]MediaType{contentType: {Schema: &Schema{Type: "string"}}}
+		}
+	default:
+		success.Content = map[string]MediaType{mediaType: {Schema: g.envelope(d.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	if d.SCIM {
+		op.Responses["default"] = Response{
+			Description: "Error",
+			Content:     map[string]MediaType{mediaType: {Schema: g.schema(reflect.TypeOf(models.SCIMError{}))}},
+		}
+	} else {
+		op.Responses["default"] = Response{
+			Description: "Error; application/problem+json when the client asks for it or ERROR_FORMAT is problem",
+			Content: map[string]MediaType{
+				"application/json":         {Schema: ref("APIResponse")},
+				"application/problem+json": {Schema: ref("ProblemDetails")},
+			},
+		}
+	}
+	return op
+}
+
+// envelope is the success response wrapping data in APIResponse
+func (g *generator) envelope(data any) *Schema {
+	if data == nil {
+		return ref("APIResponse")
+	}
+	return &Schema{AllOf: []*Schema{
+		ref("APIResponse"),
+		{
+			Type:       "object",
+			Properties: map[string]*Schema{"data": g.schema(reflect.TypeOf(data))},
+			Required:   []string{"data"},
+		},
+	}}
+}
+
+// convertPath turns gin's :param segments into {param} and describes them
+func convertPath(path string, stringParams []string) (string, []Parameter) {
+	segments := strings.Split(path, "/")
+	var params []Parameter
+	for i, segment := range segments {
+		if len(segment) < 2 || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		name := segment[1:]
+		schema := &Schema{Type: "integer", Minimum: float(1)}
+		if slices.Contains(stringParams, name) {
+			schema = &Schema{Type: "string"}
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationIDs names operations after their handler methods, e.g.
+// go-crud-employee/handlers.(*EmployeeHandler).GetEmployee-fm becomes
+// GetEmployee. A method name used by several handlers is prefixed with the
+// handler type; closures and handlers serving several routes are named
+// after the method and path instead, so every ID is unique.
+func operationIDs(routes gin.RoutesInfo) map[string]string {
+	handlerRoutes := make(map[string]int)
+	methodTypes := make(map[string]map[string]bool)
+	for _, route := range routes {
+		handlerRoutes[route.Handler]++
+		receiver, method := splitHandler(route.Handler)
+		if methodTypes[method] == nil {
+			methodTypes[method] = make(map[string]bool)
+		}
+		methodTypes[method][receiver] = true
+	}
+
+	ids := make(map[string]string, len(routes))
+	for _, route := range routes {
+		receiver, method := splitHandler(route.Handler)
+		var id string
+		switch {
+		case receiver == "" || handlerRoutes[route.Handler] > 1:
+			id = pathID(route.Method, route.Path)
+		case len(methodTypes[method]) > 1:
+			id = strings.TrimSuffix(receiver, "Handler") + method
+		default:
+			id = method
+		}
+		ids[Key(route.Method, route.Path)] = id
+	}
+	return ids
+}
+
+// splitHandler returns the receiver type and method of a handler name;
+// receiver is empty for functions and closures
+func splitHandler(handler string) (receiver, method string) {
+	handler = strings.TrimSuffix(handler, "-fm")
+	i := strings.LastIndex(handler, ".")
+	method = handler[i+1:]
+	if j := strings.LastIndex(handler[:max(i, 0)], "(*"); j >= 0 && strings.HasSuffix(handler[:i], ")") {
+		receiver = handler[j+2 : i-1]
+	}
+	return receiver, method
+}
+
+// pathID builds an ID such as getHealthzReady from a method and path
+func pathID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == ':' || r == '*' || r == '-' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,283 @@
+package openapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (draft 2020-12) as used by OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"` // A type name, or a list of them for nullable values
+	Format               string             `json:"format,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+	// Nullable database types and the schema of their value
+	nullTypes = map[reflect.Type]Schema{
+		reflect.TypeOf(sql.NullString{}):  {Type: "string"},
+		reflect.TypeOf(sql.NullInt64{}):   {Type: "integer"},
+		reflect.TypeOf(sql.NullInt32{}):   {Type: "integer"},
+		reflect.TypeOf(sql.NullFloat64{}): {Type: "number"},
+		reflect.TypeOf(sql.NullBool{}):    {Type: "boolean"},
+		reflect.TypeOf(sql.NullTime{}):    {Type: "string", Format: "date-time"},
+	}
+)
+
+// generator collects the named structs it meets as component schemas
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: make(map[string]*Schema)}
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+// schema describes t; named structs become components and are referenced
+func (g *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if null, ok := nullTypes[t]; ok {
+		null.Type = []string{null.Type.(string), "null"}
+		return &null
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType, t.Kind() == reflect.Interface:
+		return &Schema{} // Any JSON value
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t.Kind() == reflect.Int64 || t.Kind() == reflect.Uint64 {
+			return &Schema{Type: "integer", Format: "int64"}
+		}
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = &Schema{} // Placeholder for recursive types
+			*g.schemas[t.Name()] = *g.object(t)
+		}
+		return ref(t.Name())
+	}
+	return &Schema{}
+}
+
+// object describes the JSON fields of a struct, including the fields of
+// embedded structs
+func (g *generator) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, schema)
+	return schema
+}
+
+func (g *generator) fields(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			g.fields(embedded, schema)
+			continue
+		}
+
+		property, required := g.field(field)
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// field describes a struct field with its binding rules applied
+func (g *generator) field(field reflect.StructField) (*Schema, bool) {
+	property := g.schema(field.Type)
+	rules := field.Tag.Get("binding")
+	if rules == "" {
+		return property, false
+	}
+
+	// Rules after dive apply to the elements
+	list := strings.Split(rules, ",")
+	dive := slices.Index(list, "dive")
+	if dive < 0 {
+		return property, constrain(property, field.Type, list)
+	}
+	required := constrain(property, field.Type, list[:dive])
+	if property.Items != nil {
+		items := *property.Items
+		constrain(&items, field.Type.Elem(), list[dive+1:])
+		property.Items = &items
+	}
+	return property, required
+}
+
+// queryParameters describes the form-tagged fields of a query struct
+func (g *generator) queryParameters(t reflect.Type) []Parameter {
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		schema, required := g.field(field)
+		params = append(params, Parameter{Name: name, In: "query", Required: required, Schema: schema})
+	}
+	return params
+}
+
+// jsonName returns the JSON name of a field; it is empty for embedded
+// structs whose fields are promoted, and ok is false for skipped fields
+func jsonName(field reflect.StructField) (name string, ok bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	if field.Anonymous && name == "" {
+		return "", true
+	}
+	if !field.IsExported() {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// constrain applies validator rules to schema and reports whether the value
+// is required. Rules without a JSON Schema counterpart are left out.
+func constrain(schema *Schema, t reflect.Type, rules []string) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	required := false
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "gte":
+			limit(t, param, &schema.Minimum, &schema.MinLength, &schema.MinItems)
+		case "max", "lte":
+			limit(t, param, &schema.Maximum, &schema.MaxLength, &schema.MaxItems)
+		case "len":
+			limit(t, param, &schema.Minimum, &schema.MinLength, &schema.MinItems)
+			limit(t, param, &schema.Maximum, &schema.MaxLength, &schema.MaxItems)
+		case "gt":
+			if n, err := strconv.ParseFloat(param, 64); err == nil && isNumber(t) {
+				schema.ExclusiveMinimum = &n
+			}
+		case "lt":
+			if n, err := strconv.ParseFloat(param, 64); err == nil && isNumber(t) {
+				schema.ExclusiveMaximum = &n
+			}
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				if n, err := strconv.ParseFloat(value, 64); err == nil && isNumber(t) {
+					schema.Enum = append(schema.Enum, n)
+				} else {
+					schema.Enum = append(schema.Enum, value)
+				}
+			}
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "uuid":
+			schema.Format = "uuid"
+		case "datetime":
+			if param == time.DateOnly {
+				schema.Format = "date"
+			}
+		}
+	}
+	return required
+}
+
+// limit sets the bound matching t's kind: the value of numbers, the length
+// of strings or the size of arrays
+func limit(t reflect.Type, param string, number **float64, length, items **int) {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return
+	}
+	switch {
+	case isNumber(t):
+		*number = float(float64(n))
+	case t.Kind() == reflect.String:
+		*length = &n
+	case t.Kind() == reflect.Slice, t.Kind() == reflect.Array:
+		*items = &n
+	}
+}
+
+func isNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package openapi
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+//go:embed ui
+var ui embed.FS
+
+// UI is the Swagger UI distribution, configured to load /openapi.json
+var UI fs.FS = overlayFS{mustSub(ui, "ui"), swaggerFiles.FS}
+
+// overlayFS opens a file from the first file system that has it
+type overlayFS []fs.FS
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	for _, fsys := range o {
+		file, err := fsys.Open(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return file, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-crud-employee/database"
+)
+
+// takeQuery refills and spends a bucket in one atomic statement. SET
+// expressions see the row as it was before the update, so "refilled" is the
+// same value in every clause.
+const takeQuery = `
+	INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+	VALUES ($1, $2::double precision - 1, true, now())
+	ON CONFLICT (key) DO UPDATE SET
+		allowed = LEAST($2::double precision, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at) * $3::double precision) >= 1,
+		tokens = LEAST($2::double precision, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at) * $3::double precision)
+			- CASE WHEN LEAST($2::double precision, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at) * $3::double precision) >= 1 THEN 1 ELSE 0 END,
+		updated_at = now()
+	RETURNING tokens, allowed`
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every
+// replica enforces the same limits
+type PostgresStore struct {
+	db *database.DB
+}
+
+func NewPostgresStore(db *database.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Take implements Store
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	var tokens float64
+	var allowed bool
+	err := s.db.QueryRowContext(ctx, takeQuery, key, policy.Burst, policy.Rate).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %v", err)
+	}
+	return result(allowed, tokens, policy), nil
+}
+
+// Prune implements Store
+func (s *PostgresStore) Prune(ctx context.Context, before time.Time) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM rate_limit_buckets WHERE updated_at < $1", before)
+	if err != nil {
+		return fmt.Errorf("failed to prune rate limit buckets: %v", err)
+	}
+	return nil
+}
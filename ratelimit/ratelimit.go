@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Policy is a token bucket: Burst requests at once, refilled at Rate
+// requests per second
+type Policy struct {
+	Rate  float64
+	Burst int
+}
+
+// Result describes the bucket after one request was counted
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // Until the next request would be allowed; 0 when allowed
+	Reset      time.Duration // Until the bucket is full again
+}
+
+// Store keeps token buckets. Implementations must take a token atomically so
+// concurrent requests cannot overspend a bucket.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+	// Prune drops buckets untouched since before, which are full by then
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// Limiter applies named policies, one per route group
+type Limiter struct {
+	store    Store
+	policies map[string]Policy
+}
+
+// NewLimiter creates a limiter. Groups without a policy fall back to the
+// "default" policy, and are unlimited if there is none.
+func NewLimiter(store Store, policies map[string]Policy) *Limiter {
+	return &Limiter{store: store, policies: policies}
+}
+
+// Policy returns the policy of a route group
+func (l *Limiter) Policy(group string) (Policy, bool) {
+	if policy, ok := l.policies[group]; ok {
+		return policy, true
+	}
+	policy, ok := l.policies["default"]
+	return policy, ok
+}
+
+// Take counts one request of key against the group's policy
+func (l *Limiter) Take(ctx context.Context, group, key string, policy Policy) (Result, error) {
+	return l.store.Take(ctx, group+":"+key, policy)
+}
+
+// Prune drops idle buckets; policies refill completely within the longest
+// refill time, so older buckets carry no state
+func (l *Limiter) Prune(ctx context.Context) error {
+	return l.store.Prune(ctx, time.Now().Add(-l.maxRefill()))
+}
+
+func (l *Limiter) maxRefill() time.Duration {
+	longest := time.Minute
+	for _, policy := range l.policies {
+		if refill := durationFor(float64(policy.Burst), policy.Rate); refill > longest {
+			longest = refill
+		}
+	}
+	return longest
+}
+
+// result builds a Result from the tokens left after a request
+func result(allowed bool, tokens float64, policy Policy) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     policy.Burst,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     durationFor(float64(policy.Burst)-tokens, policy.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = durationFor(1-tokens, policy.Rate)
+	}
+	return res
+}
+
+// durationFor is how long it takes to refill the given number of tokens
+func durationFor(tokens, rate float64) time.Duration {
+	if tokens <= 0 || rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
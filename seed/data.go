@@ -0,0 +1,101 @@
+package seed
+
+// Given names and family names from across Indonesia. Many Indonesians go by
+// a single name, so family names are optional.
+var givenNames = []string{
+	"Adi", "Agus", "Ahmad", "Aisyah", "Andi", "Anisa", "Arif", "Ayu", "Bagus", "Bambang",
+	"Bayu", "Budi", "Cahya", "Citra", "Dani", "Dewi", "Dian", "Dimas", "Eka", "Endang",
+	"Fajar", "Fitri", "Gilang", "Hadi", "Hendra", "Indah", "Intan", "Irfan", "Joko", "Kartika",
+	"Kurniawan", "Lestari", "Maya", "Muhammad", "Nanda", "Nur", "Nurul", "Putri", "Putu", "Rahmat",
+	"Rani", "Ratna", "Reza", "Rizky", "Sari", "Siti", "Sri", "Taufik", "Tri", "Wahyu",
+	"Wayan", "Wulan", "Yanti", "Yoga", "Yusuf", "Zainal", "Ketut", "Made", "Asep", "Ujang",
+	"Dedi", "Neneng", "Ucok", "Butet", "Daeng", "Teuku", "Cut", "Ikhsan", "Galih", "Larasati",
+}
+
+var familyNames = []string{
+	"Pratama", "Saputra", "Wijaya", "Santoso", "Kusuma", "Hidayat", "Nugroho", "Setiawan", "Wibowo", "Susanto",
+	"Rahman", "Halim", "Gunawan", "Permana", "Suryadi", "Hakim", "Firmansyah", "Siregar", "Nasution", "Simanjuntak",
+	"Sitompul", "Harahap", "Lubis", "Tanjung", "Sinaga", "Pangaribuan", "Hutapea", "Manurung", "Tambunan", "Ginting",
+	"Sembiring", "Tarigan", "Purba", "Damanik", "Lumbantobing", "Sihombing", "Situmorang", "Wirawan", "Sulistyo", "Handoko",
+	"Utomo", "Purnomo", "Hartono", "Mahendra", "Wardhana", "Anggraini", "Rahmawati", "Maharani", "Ramadhan", "Syahputra",
+	"Sudirman", "Mulyadi", "Sutanto", "Tanoto", "Lim", "Tan", "Wenas", "Pattiasina", "Latuconsina", "Mandagi",
+}
+
+// Mobile number prefixes of the major Indonesian operators
+var phonePrefixes = []string{
+	"0811", "0812", "0813", "0821", "0822", "0852", "0853", "0814", "0815", "0816",
+	"0855", "0856", "0857", "0858", "0817", "0818", "0819", "0859", "0877", "0878",
+	"0895", "0896", "0897", "0898", "0899", "0881", "0882", "0883", "0887", "0888",
+}
+
+// position is a job title and its monthly salary range in rupiah
+type position struct {
+	title     string
+	minSalary float64
+	maxSalary float64
+}
+
+// department lists its positions from most to least common; weight sets how
+// often employees land in it
+type department struct {
+	name      string
+	weight    int
+	positions []position
+}
+
+var departments = []department{
+	{"Teknologi Informasi", 18, []position{
+		{"Software Engineer", 9_000_000, 18_000_000},
+		{"Senior Software Engineer", 18_000_000, 32_000_000},
+		{"QA Engineer", 7_000_000, 14_000_000},
+		{"DevOps Engineer", 12_000_000, 25_000_000},
+		{"IT Support", 5_000_000, 8_500_000},
+		{"Data Analyst", 8_000_000, 16_000_000},
+		{"Engineering Manager", 30_000_000, 55_000_000},
+	}},
+	{"Keuangan", 10, []position{
+		{"Staf Akuntansi", 5_500_000, 9_000_000},
+		{"Akuntan", 8_000_000, 14_000_000},
+		{"Staf Pajak", 6_000_000, 10_000_000},
+		{"Analis Keuangan", 10_000_000, 18_000_000},
+		{"Manajer Keuangan", 22_000_000, 40_000_000},
+	}},
+	{"Sumber Daya Manusia", 7, []position{
+		{"Staf Rekrutmen", 5_000_000, 8_500_000},
+		{"HR Generalist", 6_500_000, 11_000_000},
+		{"Staf Payroll", 5_500_000, 9_000_000},
+		{"Manajer SDM", 20_000_000, 35_000_000},
+	}},
+	{"Pemasaran", 10, []position{
+		{"Staf Pemasaran", 5_000_000, 8_500_000},
+		{"Digital Marketing Specialist", 7_000_000, 13_000_000},
+		{"Content Writer", 5_000_000, 9_000_000},
+		{"Brand Manager", 18_000_000, 32_000_000},
+	}},
+	{"Penjualan", 16, []position{
+		{"Sales Executive", 4_800_000, 9_000_000},
+		{"Account Manager", 9_000_000, 17_000_000},
+		{"Key Account Manager", 15_000_000, 26_000_000},
+		{"Manajer Penjualan", 22_000_000, 40_000_000},
+	}},
+	{"Operasional", 20, []position{
+		{"Staf Operasional", 4_500_000, 7_500_000},
+		{"Staf Gudang", 4_200_000, 6_500_000},
+		{"Koordinator Logistik", 7_000_000, 12_000_000},
+		{"Supervisor Operasional", 9_000_000, 15_000_000},
+		{"Manajer Operasional", 20_000_000, 36_000_000},
+	}},
+	{"Layanan Pelanggan", 12, []position{
+		{"Customer Service", 4_500_000, 7_000_000},
+		{"Team Leader Customer Service", 7_500_000, 11_000_000},
+		{"Manajer Layanan Pelanggan", 16_000_000, 28_000_000},
+	}},
+	{"Hukum", 3, []position{
+		{"Staf Legal", 7_000_000, 12_000_000},
+		{"Legal Counsel", 15_000_000, 28_000_000},
+	}},
+	{"Pengadaan", 4, []position{
+		{"Staf Pengadaan", 5_500_000, 9_000_000},
+		{"Manajer Pengadaan", 18_000_000, 32_000_000},
+	}},
+}
@@ -0,0 +1,130 @@
+// Package seed generates realistic fake employees and users for development
+// and load testing.
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// EmailDomain marks seeded rows, so later runs can continue numbering after
+// them
+const EmailDomain = "seed.example.com"
+
+// Hire dates fall in this range so the same seed always yields the same rows
+var (
+	firstHireDate = time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastHireDate  = time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// EmployeeColumns are the employees columns in the order Employee returns
+// them
+var EmployeeColumns = []string{"nip", "name", "email", "phone", "position", "department", "salary", "hire_date", "is_active", "created_at", "updated_at"}
+
+// UserColumns are the users columns in the order User returns them
+var UserColumns = []string{"username", "email", "password_hash", "role", "is_active", "created_at", "updated_at"}
+
+// Generator derives each row from the seed and the row number alone, so rows
+// can be generated in any order or in separate runs and still match
+type Generator struct {
+	seed            uint64
+	departmentTotal int
+}
+
+// NewGenerator returns a generator for seed
+func NewGenerator(seed uint64) *Generator {
+	total := 0
+	for _, dept := range departments {
+		total += dept.weight
+	}
+	return &Generator{seed: seed, departmentTotal: total}
+}
+
+// rand returns the random source for row n of a table
+func (g *Generator) rand(table uint64, n int) *rand.Rand {
+	return rand.New(rand.NewPCG(g.seed^table, uint64(n)))
+}
+
+// name returns a full name and its email local part
+func name(r *rand.Rand) (string, string) {
+	given := givenNames[r.IntN(len(givenNames))]
+	if r.IntN(100) < 15 {
+		return given, strings.ToLower(given)
+	}
+	family := familyNames[r.IntN(len(familyNames))]
+	return given + " " + family, strings.ToLower(given + "." + family)
+}
+
+// Employee returns row n (from 1) of the employees table. NIPs and emails
+// carry n, so they are unique for distinct n.
+func (g *Generator) Employee(n int) []any {
+	r := g.rand(1, n)
+	fullName, local := name(r)
+
+	dept := g.department(r)
+	// Earlier positions are more common: junior staff outnumber managers
+	pos := dept.positions[min(r.IntN(len(dept.positions)), r.IntN(len(dept.positions)))]
+
+	days := int(lastHireDate.Sub(firstHireDate).Hours() / 24)
+	hireDate := firstHireDate.AddDate(0, 0, r.IntN(days+1))
+
+	// Pay rises about 3% for every year of tenure, rounded to Rp 50.000
+	years := lastHireDate.Sub(hireDate).Hours() / 24 / 365
+	salary := pos.minSalary + r.Float64()*(pos.maxSalary-pos.minSalary)
+	salary = float64(int(salary*(1+0.03*years)/50_000)) * 50_000
+
+	var phone any
+	if r.IntN(100) < 90 {
+		phone = fmt.Sprintf("%s%08d", phonePrefixes[r.IntN(len(phonePrefixes))], r.IntN(100_000_000))
+	}
+
+	createdAt := hireDate.Add(time.Duration(8+r.IntN(9)) * time.Hour)
+	return []any{
+		fmt.Sprintf("EMP%08d", n),
+		fullName,
+		fmt.Sprintf("%s.%d@%s", local, n, EmailDomain),
+		phone,
+		pos.title,
+		dept.name,
+		salary,
+		hireDate,
+		r.IntN(100) < 93,
+		createdAt,
+		createdAt,
+	}
+}
+
+// User returns row n (from 1) of the users table. Every user gets
+// passwordHash, since hashing a password per row would dominate the run.
+func (g *Generator) User(n int, passwordHash string) []any {
+	r := g.rand(2, n)
+	_, local := name(r)
+
+	days := int(lastHireDate.Sub(firstHireDate).Hours() / 24)
+	createdAt := firstHireDate.AddDate(0, 0, r.IntN(days+1)).Add(time.Duration(r.IntN(86400)) * time.Second)
+
+	username := fmt.Sprintf("%s%d", local, n)
+	return []any{
+		username,
+		username + "@" + EmailDomain,
+		passwordHash,
+		"user",
+		r.IntN(100) < 95,
+		createdAt,
+		createdAt,
+	}
+}
+
+// department picks a department by weight
+func (g *Generator) department(r *rand.Rand) department {
+	pick := r.IntN(g.departmentTotal)
+	for _, dept := range departments {
+		if pick < dept.weight {
+			return dept
+		}
+		pick -= dept.weight
+	}
+	return departments[len(departments)-1]
+}
@@ -0,0 +1,146 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-crud-employee/config"
+)
+
+// touch moves the files' modification time forward, as a renewal would
+func touch(t *testing.T, at time.Time, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		if err := os.Chtimes(file, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func serial(t *testing.T, certPEM []byte) string {
+	t.Helper()
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.SerialNumber.String()
+}
+
+// servedSerial connects to addr and returns the serial number of the
+// certificate the server presents to a new connection
+func servedSerial(t *testing.T, addr string, roots *x509.CertPool) string {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.String()
+}
+
+func TestCertReloader(t *testing.T) {
+	ca := newCA(t, "test CA")
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.CertPEM())
+
+	firstCert, firstKey, err := ca.IssueServer("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := writePair(t, t.TempDir(), firstCert, firstKey)
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertReloader: %v", err)
+	}
+	addr := serveTLS(t, config.TLSConfig{MinVersion: "1.2", ClientAuth: "none"}, reloader)
+
+	if got := servedSerial(t, addr, roots); got != serial(t, firstCert) {
+		t.Fatalf("served %s, want the first certificate", got)
+	}
+
+	// Files whose modification time did not change are not read again
+	loaded, err := reloader.stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, loaded[0], certFile)
+	if err := reloader.Reload(); err != nil {
+		t.Errorf("Reload of unchanged files: %v", err)
+	}
+
+	// A broken pair keeps the last good certificate
+	touch(t, time.Now().Add(time.Minute), certFile, keyFile)
+	if err := reloader.Reload(); err == nil {
+		t.Error("Reload of a broken pair succeeded")
+	}
+	if got := servedSerial(t, addr, roots); got != serial(t, firstCert) {
+		t.Fatalf("served %s after a failed reload, want the first certificate", got)
+	}
+
+	// A renewed pair is served to new connections
+	secondCert, secondKey, err := ca.IssueServer("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePair(t, filepath.Dir(certFile), secondCert, secondKey)
+	touch(t, time.Now().Add(2*time.Minute), certFile, keyFile)
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := servedSerial(t, addr, roots); got != serial(t, secondCert) {
+		t.Fatalf("served %s, want the renewed certificate", got)
+	}
+}
+
+func TestCertReloaderWatch(t *testing.T) {
+	ca := newCA(t, "test CA")
+	firstCert, firstKey, err := ca.IssueServer("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := writePair(t, t.TempDir(), firstCert, firstKey)
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := reloader.current()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 10*time.Millisecond)
+
+	renewedCert, renewedKey, err := ca.IssueServer("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePair(t, filepath.Dir(certFile), renewedCert, renewedKey)
+	touch(t, time.Now().Add(time.Minute), certFile, keyFile)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for reloader.current() == before {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch did not pick up the renewed certificate")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := reloader.current().Leaf.SerialNumber.String(); got != serial(t, renewedCert) {
+		t.Errorf("loaded %s, want the renewed certificate", got)
+	}
+}
+
+func TestNewCertReloaderMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewCertReloader(dir+"/missing.pem", dir+"/missing-key.pem"); err == nil {
+		t.Error("NewCertReloader succeeded without files")
+	}
+}
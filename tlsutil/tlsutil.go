@@ -0,0 +1,77 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go-crud-employee/config"
+)
+
+// MinVersions maps TLS_MIN_VERSION values to protocol versions
+var MinVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ClientAuthModes maps TLS_CLIENT_AUTH values to client certificate policies
+var ClientAuthModes = map[string]tls.ClientAuthType{
+	"none":     tls.NoClientCert,
+	"optional": tls.VerifyClientCertIfGiven,
+	"require":  tls.RequireAndVerifyClientCert,
+}
+
+// ServerConfig builds the listener's TLS configuration. Certificates come
+// from reloader so renewals apply to new connections.
+func ServerConfig(cfg config.TLSConfig, reloader *CertReloader) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:     MinVersions[cfg.MinVersion],
+		GetCertificate: reloader.GetCertificate,
+		ClientAuth:     ClientAuthModes[cfg.ClientAuth],
+	}
+
+	// Cipher suites only apply up to TLS 1.2; Go picks TLS 1.3 suites itself
+	for _, name := range cfg.CipherSuites {
+		id, ok := CipherSuite(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS cipher suite %q", name)
+		}
+		tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client CA file %s contains no certificates", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// CipherSuite looks up a secure cipher suite by its standard name, e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+func CipherSuite(name string) (uint16, bool) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return suite.ID, true
+		}
+	}
+	return 0, false
+}
+
+// ClientCommonName returns the common name of the verified client
+// certificate, or "" when the client presented none
+func ClientCommonName(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName
+}
@@ -0,0 +1,177 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-crud-employee/config"
+)
+
+// writePair writes a certificate and key into dir and returns their paths
+func writePair(t *testing.T, dir string, certPEM, keyPEM []byte) (string, string) {
+	t.Helper()
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func newCA(t *testing.T, name string) *CA {
+	t.Helper()
+	ca, err := NewCA(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+func clientPair(t *testing.T, ca *CA, commonName string) tls.Certificate {
+	t.Helper()
+	certPEM, keyPEM, err := ca.IssueClient(commonName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// serveTLS serves the client's common name over TLS with the API's server
+// configuration and returns the address
+func serveTLS(t *testing.T, cfg config.TLSConfig, reloader *CertReloader) string {
+	t.Helper()
+	tlsConfig, err := ServerConfig(cfg, reloader)
+	if err != nil {
+		t.Fatalf("ServerConfig: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, ClientCommonName(r))
+		}),
+		ErrorLog: log.New(io.Discard, "", 0), // Rejected handshakes are expected
+	}
+	go server.Serve(tls.NewListener(listener, tlsConfig))
+	t.Cleanup(func() { server.Close() })
+	return listener.Addr().String()
+}
+
+func get(addr string, roots *x509.CertPool, certs ...tls.Certificate) (string, error) {
+	// Present the certificate even when the server does not list its CA
+	// as acceptable, as a misconfigured client would
+	tlsConfig := &tls.Config{RootCAs: roots}
+	if len(certs) > 0 {
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &certs[0], nil
+		}
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	defer client.CloseIdleConnections()
+
+	response, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	return string(body), err
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newCA(t, "test CA")
+	dir := t.TempDir()
+	certPEM, keyPEM, err := ca.IssueServer("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloader, err := NewCertReloader(writePair(t, dir, certPEM, keyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, ca.CertPEM(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.CertPEM())
+
+	trusted := clientPair(t, ca, "payroll-sync")
+	untrusted := clientPair(t, newCA(t, "other CA"), "payroll-sync")
+
+	tests := []struct {
+		clientAuth string
+		certs      []tls.Certificate
+		want       string
+		ok         bool
+	}{
+		{"require", []tls.Certificate{trusted}, "payroll-sync", true},
+		{"require", nil, "", false},
+		{"require", []tls.Certificate{untrusted}, "", false},
+		{"optional", []tls.Certificate{trusted}, "payroll-sync", true},
+		{"optional", nil, "", true},
+		{"optional", []tls.Certificate{untrusted}, "", false},
+		{"none", []tls.Certificate{trusted}, "", true},
+	}
+	for _, tt := range tests {
+		addr := serveTLS(t, config.TLSConfig{MinVersion: "1.2", ClientAuth: tt.clientAuth, ClientCAFile: caFile}, reloader)
+
+		got, err := get(addr, roots, tt.certs...)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("%s with %d client certificates: %q, %v; want %q, ok %t", tt.clientAuth, len(tt.certs), got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	ca := newCA(t, "test CA")
+	dir := t.TempDir()
+	certPEM, keyPEM, err := ca.IssueServer("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloader, err := NewCertReloader(writePair(t, dir, certPEM, keyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlsConfig, err := ServerConfig(config.TLSConfig{
+		MinVersion:   "1.3",
+		ClientAuth:   "optional",
+		CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+	}, reloader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS13 || tlsConfig.ClientAuth != tls.VerifyClientCertIfGiven ||
+		len(tlsConfig.CipherSuites) != 1 || tlsConfig.CipherSuites[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+		t.Errorf("ServerConfig = %+v", tlsConfig)
+	}
+
+	notPEM := filepath.Join(dir, "empty.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0o600)
+	for name, cfg := range map[string]config.TLSConfig{
+		"unknown cipher suite":  {CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+		"missing client CA":     {ClientCAFile: filepath.Join(dir, "missing.pem")},
+		"client CA without PEM": {ClientCAFile: notPEM},
+	} {
+		if _, err := ServerConfig(cfg, reloader); err == nil {
+			t.Errorf("%s: ServerConfig succeeded, want an error", name)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -22,23 +24,36 @@ func NewJWTManager(cfg *config.Config) *JWTManager {
 	}
 }
 
-// GenerateToken generates a new JWT token for the user
-func (j *JWTManager) GenerateToken(user *models.User) (string, time.Time, error) {
+// GenerateSessionID returns a random identifier used as the JWT jti claim
+func GenerateSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GenerateToken generates a new JWT token for the user bound to the given session
+func (j *JWTManager) GenerateToken(user *models.User, sessionID string) (string, time.Time, error) {
 	now := time.Now()
 	expiresAt := now.Add(j.expiry)
 
 	claims := models.JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Exp:      expiresAt.Unix(),
-		Iat:      now.Unix(),
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
+		Exp:       expiresAt.Unix(),
+		Iat:       now.Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id":  claims.UserID,
 		"username": claims.Username,
 		"email":    claims.Email,
+		"role":     claims.Role,
+		"jti":      claims.SessionID,
 		"exp":      claims.Exp,
 		"iat":      claims.Iat,
 	})
@@ -99,16 +114,27 @@ func (j *JWTManager) ValidateToken(tokenString string) (*models.JWTClaims, error
 		return nil, fmt.Errorf("invalid email claim")
 	}
 
+	// Tokens issued before roles existed carry no role claim
+	role, _ := claims["role"].(string)
+
+	// Every token must belong to a session so it can be revoked
+	sessionID, ok := claims["jti"].(string)
+	if !ok || sessionID == "" {
+		return nil, fmt.Errorf("invalid jti claim")
+	}
+
 	iat, ok := claims["iat"].(float64)
 	if !ok {
 		return nil, fmt.Errorf("invalid iat claim")
 	}
 
 	return &models.JWTClaims{
-		UserID:   int(userID),
-		Username: username,
-		Email:    email,
-		Exp:      int64(exp),
-		Iat:      int64(iat),
+		UserID:    int(userID),
+		Username:  username,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
+		Exp:       int64(exp),
+		Iat:       int64(iat),
 	}, nil
 }
\ No newline at end of file
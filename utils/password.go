@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 
 	"golang.org/x/crypto/bcrypt"
@@ -36,4 +38,13 @@ func IsValidPassword(password string) bool {
 	// For example: require uppercase, lowercase, numbers, special characters
 	
 	return true
-}
\ No newline at end of file
+}
+
+// GenerateRandomPassword returns a random URL-safe password for temporary use
+func GenerateRandomPassword() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate password: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RevocationList is an in-memory cache of revoked session IDs (JWT jti claims).
+// Entries are kept until the token they belong to would have expired anyway.
+type RevocationList struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+func NewRevocationList() *RevocationList {
+	return &RevocationList{
+		entries: make(map[string]time.Time),
+	}
+}
+
+// Revoke marks a session as revoked until expiresAt
+func (r *RevocationList) Revoke(sessionID string, expiresAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[sessionID] = expiresAt
+}
+
+// IsRevoked reports whether the session has been revoked
+func (r *RevocationList) IsRevoked(sessionID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, revoked := r.entries[sessionID]
+	return revoked
+}
+
+// Merge adds a snapshot of revoked sessions and drops entries that have expired.
+// Revocations are never undone, so existing entries are kept rather than replaced.
+func (r *RevocationList) Merge(entries map[string]time.Time) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, expiresAt := range entries {
+		r.entries[id] = expiresAt
+	}
+	for id, expiresAt := range r.entries {
+		if !expiresAt.After(now) {
+			delete(r.entries, id)
+		}
+	}
+}
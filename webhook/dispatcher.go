@@ -0,0 +1,295 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-crud-employee/config"
+	"go-crud-employee/database"
+	"go-crud-employee/metrics"
+	"go-crud-employee/models"
+)
+
+// fanOutBatch is how many outbox events one fan-out statement handles
+const fanOutBatch = 100
+
+// maxResponseBody is how much of a receiver's answer the delivery log keeps
+const maxResponseBody = 1024
+
+// fanOutQuery turns outbox events into one delivery per matching active
+// subscription and marks them dispatched in a single statement. SKIP LOCKED
+// lets every replica drain the outbox without handling an event twice.
+const fanOutQuery = `
+	WITH claimed AS (
+		SELECT id, event_type FROM outbox_events
+		WHERE dispatched_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	), fanout AS (
+		INSERT INTO webhook_deliveries (subscription_id, event_id)
+		SELECT s.id, c.id FROM claimed c
+		JOIN webhook_subscriptions s ON s.is_active AND c.event_type = ANY(s.event_types)
+	)
+	UPDATE outbox_events SET dispatched_at = now()
+	WHERE id IN (SELECT id FROM claimed)`
+
+// claimQuery leases due deliveries by pushing their next attempt past the
+// time one attempt may take. A replica that dies mid-delivery leaves them
+// to be retried once the lease runs out.
+const claimQuery = `
+	WITH due AS (
+		SELECT d.id FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id AND s.is_active
+		WHERE d.status = 'pending' AND d.next_attempt_at <= now()
+		ORDER BY d.next_attempt_at
+		LIMIT $1
+		FOR UPDATE OF d SKIP LOCKED
+	)
+	UPDATE webhook_deliveries d SET next_attempt_at = now() + $2::double precision * interval '1 second'
+	FROM due, webhook_subscriptions s, outbox_events e
+	WHERE d.id = due.id AND s.id = d.subscription_id AND e.id = d.event_id
+	RETURNING d.id, d.attempts, s.url, s.secret, e.id, e.event_type, e.payload, e.created_at`
+
+// recordQuery logs an attempt and moves its delivery to the next state
+const recordQuery = `
+	WITH logged AS (
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, response_body, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	)
+	UPDATE webhook_deliveries SET
+		attempts = $2,
+		status = $7::varchar,
+		next_attempt_at = now() + $8::double precision * interval '1 second',
+		last_status_code = $3,
+		last_error = $4,
+		delivered_at = CASE WHEN $7::varchar = 'succeeded' THEN now() END,
+		updated_at = now()
+	WHERE id = $1`
+
+// payload is the JSON body of every delivery
+type payload struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// delivery is a claimed delivery with everything needed to send it
+type delivery struct {
+	id       int64
+	attempts int
+	url      string
+	secret   string
+	event    payload
+}
+
+// Dispatcher fans outbox events out to subscriptions and delivers them,
+// retrying failures with exponential backoff until they succeed or die
+type Dispatcher struct {
+	db     *database.DB
+	cfg    config.WebhookConfig
+	client *http.Client
+}
+
+func NewDispatcher(db *database.DB, cfg config.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		db:  db,
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// A redirect could point the payload anywhere; it counts as a
+			// failed attempt instead
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// Run polls for work until ctx is canceled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := d.fanOut(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("Failed to fan out webhook events", "error", err)
+		}
+		if err := d.deliverDue(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("Failed to deliver webhooks", "error", err)
+		}
+	}
+}
+
+// fanOut creates deliveries for every undispatched outbox event
+func (d *Dispatcher) fanOut(ctx context.Context) error {
+	for {
+		result, err := d.db.ExecContext(ctx, fanOutQuery, fanOutBatch)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n < fanOutBatch {
+			return err
+		}
+	}
+}
+
+// deliverDue sends due deliveries, Concurrency at a time, until none are
+// left. Claiming only what is sent right away keeps leases short.
+func (d *Dispatcher) deliverDue(ctx context.Context) error {
+	lease := d.cfg.Timeout + time.Minute
+	for ctx.Err() == nil {
+		deliveries, err := d.claim(ctx, lease)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		for _, job := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.deliver(ctx, job)
+			}()
+		}
+		wg.Wait()
+
+		if len(deliveries) < d.cfg.Concurrency {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) claim(ctx context.Context, lease time.Duration) ([]delivery, error) {
+	rows, err := d.db.QueryContext(ctx, claimQuery, d.cfg.Concurrency, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []delivery
+	for rows.Next() {
+		var job delivery
+		var data []byte
+		err := rows.Scan(&job.id, &job.attempts, &job.url, &job.secret, &job.event.ID, &job.event.Type, &data, &job.event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %v", err)
+		}
+		job.event.Data = data
+		deliveries = append(deliveries, job)
+	}
+	return deliveries, rows.Err()
+}
+
+// deliver makes one attempt and records its outcome
+func (d *Dispatcher) deliver(ctx context.Context, job delivery) {
+	body, err := json.Marshal(job.event)
+	if err != nil {
+		slog.Error("Failed to encode webhook payload", "delivery_id", job.id, "error", err)
+		return
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, job.url, bytes.NewReader(body))
+	if err != nil {
+		d.record(ctx, job, 0, err.Error(), "", 0)
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "go-crud-employee-webhooks/1.0")
+	request.Header.Set(HeaderEventID, fmt.Sprint(job.event.ID))
+	request.Header.Set(HeaderEventType, job.event.Type)
+	request.Header.Set(HeaderSignature, Sign(job.secret, time.Now().Unix(), body))
+
+	start := time.Now()
+	response, err := d.client.Do(request)
+	duration := time.Since(start)
+	if ctx.Err() != nil {
+		// Shutting down; the lease runs out and the attempt is made again
+		return
+	}
+	if err != nil {
+		d.record(ctx, job, 0, err.Error(), "", duration)
+		return
+	}
+	defer response.Body.Close()
+
+	answer, _ := io.ReadAll(io.LimitReader(response.Body, maxResponseBody))
+	errText := ""
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		errText = fmt.Sprintf("unexpected status %d", response.StatusCode)
+	}
+	d.record(ctx, job, response.StatusCode, errText, string(bytes.ToValidUTF8(answer, nil)), duration)
+}
+
+// record stores an attempt and moves the delivery on as nextState decides
+func (d *Dispatcher) record(ctx context.Context, job delivery, statusCode int, errText, answer string, duration time.Duration) {
+	attempt := job.attempts + 1
+	status, delay := d.nextState(attempt, errText != "")
+	result := status
+	switch status {
+	case models.DeliveryPending:
+		result = "failed"
+	case models.DeliveryDead:
+		slog.Warn("Webhook delivery dead", "delivery_id", job.id, "url", job.url, "attempts", attempt, "error", errText)
+	}
+
+	var code any
+	if statusCode != 0 {
+		code = statusCode
+	}
+	_, err := d.db.ExecContext(ctx, recordQuery, job.id, attempt, code, errText, answer, duration.Milliseconds(), status, delay.Seconds())
+	if err != nil {
+		slog.Error("Failed to record webhook attempt", "delivery_id", job.id, "error", err)
+		return
+	}
+	metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+}
+
+// nextState decides what follows an attempt: success ends the delivery, a
+// failure is retried after retryDelay until MaxAttempts have been made
+func (d *Dispatcher) nextState(attempt int, failed bool) (status string, delay time.Duration) {
+	switch {
+	case !failed:
+		return models.DeliverySucceeded, 0
+	case attempt >= d.cfg.MaxAttempts:
+		return models.DeliveryDead, 0
+	default:
+		return models.DeliveryPending, d.retryDelay(attempt)
+	}
+}
+
+// retryDelay adds up to 20% jitter to the backoff, so receivers coming
+// back up are not hit by every retry at once
+func (d *Dispatcher) retryDelay(attempt int) time.Duration {
+	delay := d.backoff(attempt)
+	jitter := time.Duration(rand.Int64N(int64(delay)/5 + 1))
+	return delay + min(jitter, math.MaxInt64-delay)
+}
+
+// backoff is RetryBackoff doubled for every attempt after the first, up to
+// RetryBackoffMax. Doubling stops at the cap, so it cannot overflow.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.RetryBackoff
+	for i := 1; i < attempt && delay < d.cfg.RetryBackoffMax; i++ {
+		if delay > d.cfg.RetryBackoffMax/2 {
+			return d.cfg.RetryBackoffMax
+		}
+		delay *= 2
+	}
+	return min(delay, d.cfg.RetryBackoffMax)
+}
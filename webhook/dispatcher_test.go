@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-crud-employee/config"
+	"go-crud-employee/database/dbtest"
+	"go-crud-employee/models"
+)
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{cfg: config.WebhookConfig{RetryBackoff: 30 * time.Second, RetryBackoffMax: 6 * time.Hour}}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{9, 128 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{64, 6 * time.Hour},
+		{math.MaxInt, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	// Doubling past half the largest duration would overflow
+	d.cfg = config.WebhookConfig{RetryBackoff: time.Nanosecond, RetryBackoffMax: math.MaxInt64}
+	for _, attempt := range []int{62, 63, 64, 100} {
+		if got := d.backoff(attempt); got <= 0 {
+			t.Errorf("backoff(%d) = %v, want a positive duration", attempt, got)
+		}
+		if got := d.retryDelay(attempt); got <= 0 {
+			t.Errorf("retryDelay(%d) = %v, want a positive duration", attempt, got)
+		}
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	d := &Dispatcher{cfg: config.WebhookConfig{RetryBackoff: time.Minute, RetryBackoffMax: time.Hour}}
+
+	for range 100 {
+		if got := d.retryDelay(2); got < 2*time.Minute || got > 2*time.Minute+24*time.Second {
+			t.Fatalf("retryDelay(2) = %v, want 2m plus at most 20%%", got)
+		}
+	}
+}
+
+func TestNextState(t *testing.T) {
+	d := &Dispatcher{cfg: config.WebhookConfig{MaxAttempts: 3, RetryBackoff: time.Second, RetryBackoffMax: time.Minute}}
+
+	tests := []struct {
+		attempt int
+		failed  bool
+		status  string
+		retry   bool
+	}{
+		{1, false, models.DeliverySucceeded, false},
+		{3, false, models.DeliverySucceeded, false},
+		{1, true, models.DeliveryPending, true},
+		{2, true, models.DeliveryPending, true},
+		{3, true, models.DeliveryDead, false},
+		{4, true, models.DeliveryDead, false},
+	}
+	for _, tt := range tests {
+		status, delay := d.nextState(tt.attempt, tt.failed)
+		if status != tt.status || (delay > 0) != tt.retry {
+			t.Errorf("nextState(%d, %t) = %s, %v; want %s, retry %t", tt.attempt, tt.failed, status, delay, tt.status, tt.retry)
+		}
+	}
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "webhook_subscriptions", "outbox_events")
+	ctx := context.Background()
+
+	// The receiver fails until told otherwise and checks every signature
+	var healthy atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature := r.Header.Get(HeaderSignature)
+		timestamp, _ := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+		if signature != Sign("whsec_test", timestamp, body) || r.Header.Get(HeaderEventType) != models.EventEmployeeCreated {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		if !healthy.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		var event payload
+		json.Unmarshal(body, &event)
+		w.Write([]byte("ok " + strconv.FormatInt(event.ID, 10)))
+	}))
+	defer receiver.Close()
+
+	_, err := db.ExecContext(ctx, `INSERT INTO webhook_subscriptions (url, secret, event_types) VALUES ($1, 'whsec_test', '{employee.created}')`, receiver.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue := func() {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		if err := Enqueue(ctx, tx, Event{Type: models.EventEmployeeCreated, Data: map[string]int{"id": 1}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDispatcher(db, config.WebhookConfig{
+		Timeout: 5 * time.Second, Concurrency: 4, MaxAttempts: 2,
+		RetryBackoff: time.Hour, RetryBackoffMax: time.Hour,
+	})
+	run := func() {
+		t.Helper()
+		if err := d.fanOut(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.deliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state := func() (status string, attempts int) {
+		t.Helper()
+		err := db.QueryRowContext(ctx, "SELECT status, attempts FROM webhook_deliveries ORDER BY id DESC LIMIT 1").Scan(&status, &attempts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return status, attempts
+	}
+	makeDue := func() {
+		t.Helper()
+		if _, err := db.ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = now()"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A failure is retried later, not right away, and dies after MaxAttempts
+	enqueue()
+	run()
+	if status, attempts := state(); status != models.DeliveryPending || attempts != 1 {
+		t.Fatalf("after one failure: %s after %d attempts, want pending after 1", status, attempts)
+	}
+	run()
+	if _, attempts := state(); attempts != 1 {
+		t.Fatalf("retried before the backoff: %d attempts", attempts)
+	}
+	makeDue()
+	run()
+	if status, attempts := state(); status != models.DeliveryDead || attempts != 2 {
+		t.Fatalf("after two failures: %s after %d attempts, want dead after 2", status, attempts)
+	}
+
+	// A later event reaches the receiver once it is back up
+	healthy.Store(true)
+	enqueue()
+	run()
+	if status, attempts := state(); status != models.DeliverySucceeded || attempts != 1 {
+		t.Fatalf("after success: %s after %d attempts, want succeeded after 1", status, attempts)
+	}
+
+	var logged int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_delivery_attempts").Scan(&logged); err != nil {
+		t.Fatal(err)
+	}
+	if logged != 3 {
+		t.Errorf("logged %d attempts, want 3", logged)
+	}
+}
@@ -0,0 +1,82 @@
+// Package webhook delivers employee lifecycle events to subscribed URLs.
+// Events go through a transactional outbox: handlers write them in the same
+// transaction as the change they describe, and the Dispatcher sends them
+// after commit, so no event is lost or sent for a rolled back change.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-crud-employee/database"
+	"go-crud-employee/models"
+
+	"github.com/lib/pq"
+)
+
+// NotifyChannel is the Postgres channel notified when outbox events commit
+const NotifyChannel = "employee_events"
+
+// Event is a change to record in the outbox
+type Event struct {
+	Type string
+	Data any // Marshaled to JSON as the payload's "data"
+}
+
+// EmployeeEvent carries employee as it is after the change
+func EmployeeEvent(eventType string, employee models.EmployeeResponse) Event {
+	return Event{Type: eventType, Data: employee}
+}
+
+// UpdateEventType tells a deactivation apart from other updates
+func UpdateEventType(wasActive, isActive bool) string {
+	if wasActive && !isActive {
+		return models.EventEmployeeDeactivated
+	}
+	return models.EventEmployeeUpdated
+}
+
+// Enqueue writes events to the outbox within tx
+func Enqueue(ctx context.Context, tx *database.Tx, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	types := make([]string, len(events))
+	payloads := make([]string, len(events))
+	for i, event := range events {
+		payload, err := json.Marshal(event.Data)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %v", event.Type, err)
+		}
+		types[i], payloads[i] = event.Type, string(payload)
+	}
+
+	_, err := tx.ExecContext(ctx, "INSERT INTO outbox_events (event_type, payload) SELECT * FROM unnest($1::text[], $2::jsonb[])",
+		pq.Array(types), pq.Array(payloads))
+	if err != nil {
+		return fmt.Errorf("failed to write outbox events: %v", err)
+	}
+
+	// Delivered on commit only
+	if _, err := tx.ExecContext(ctx, "SELECT pg_notify($1, '')", NotifyChannel); err != nil {
+		return fmt.Errorf("failed to notify outbox listeners: %v", err)
+	}
+	return nil
+}
+
+// Prune drops events older than retention, with their deliveries and log,
+// unless a delivery is still pending. The outbox doubles as the log that
+// event streams resume from, so it is pruned whether or not webhooks run.
+func Prune(ctx context.Context, db *database.DB, retention time.Duration) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM outbox_events e
+		WHERE e.created_at < now() - $1::double precision * interval '1 second'
+			AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.event_id = e.id AND d.status = 'pending')`,
+		retention.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to prune outbox events: %v", err)
+	}
+	return nil
+}
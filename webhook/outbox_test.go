@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"go-crud-employee/database/dbtest"
+	"go-crud-employee/models"
+)
+
+func TestUpdateEventType(t *testing.T) {
+	tests := []struct {
+		wasActive, isActive bool
+		want                string
+	}{
+		{true, false, models.EventEmployeeDeactivated},
+		{true, true, models.EventEmployeeUpdated},
+		{false, false, models.EventEmployeeUpdated},
+		{false, true, models.EventEmployeeUpdated},
+	}
+	for _, tt := range tests {
+		if got := UpdateEventType(tt.wasActive, tt.isActive); got != tt.want {
+			t.Errorf("UpdateEventType(%t, %t) = %s, want %s", tt.wasActive, tt.isActive, got, tt.want)
+		}
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	db := dbtest.Open(t)
+	dbtest.Truncate(t, db, "outbox_events")
+	ctx := context.Background()
+
+	write := func(commit bool, events ...Event) {
+		t.Helper()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		if err := Enqueue(ctx, tx, events...); err != nil {
+			t.Fatal(err)
+		}
+		if commit {
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Events of a rolled back change are never written
+	write(false, Event{Type: models.EventEmployeeCreated, Data: map[string]string{"name": "rolled back"}})
+	write(true,
+		Event{Type: models.EventEmployeeCreated, Data: map[string]string{"name": "Rina"}},
+		Event{Type: models.EventEmployeeUpdated, Data: map[string]string{"name": "Rina W"}},
+	)
+	write(true)
+
+	rows, err := db.QueryContext(ctx, "SELECT event_type, payload->>'name' FROM outbox_events ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var eventType, name string
+		if err := rows.Scan(&eventType, &name); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, eventType+" "+name)
+	}
+	want := []string{models.EventEmployeeCreated + " Rina", models.EventEmployeeUpdated + " Rina W"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("outbox = %v, want %v", got, want)
+	}
+}
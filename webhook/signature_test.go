@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":1}`)
+
+	got := Sign("whsec_test", 1700000000, body)
+	want := "t=1700000000,v1=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+
+	// Every signed input changes the signature
+	for name, other := range map[string]string{
+		"secret":    Sign("whsec_other", 1700000000, body),
+		"timestamp": Sign("whsec_test", 1700000001, body),
+		"body":      Sign("whsec_test", 1700000000, []byte(`{"id":2}`)),
+	} {
+		_, mac, _ := strings.Cut(other, ",v1=")
+		if strings.HasSuffix(want, mac) {
+			t.Errorf("changing the %s kept the signature", name)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(a, "whsec_") || len(a) != len("whsec_")+64 {
+		t.Errorf("secret %q is not whsec_ and 32 hex-encoded bytes", a)
+	}
+	if a == b {
+		t.Error("two generated secrets are equal")
+	}
+}